@@ -0,0 +1,104 @@
+package oaipmh
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// Name returns the name of the root element held in the container, which
+// identifies the schema it follows.
+func (a About) Name() (xml.Name, error) {
+	return rootName(a.Raw)
+}
+
+func (a About) Decode(into interface{}) error {
+	return xml.Unmarshal(a.Raw, into)
+}
+
+// Provenance decodes the first provenance container on the record, returning
+// nil when there is none.
+func (r Record) Provenance() (*Provenance, error) {
+	provenance := new(Provenance)
+	found, err := r.decodeAbout(provenanceNamespace, provenance)
+
+	if !found {
+		return nil, err
+	}
+
+	return provenance, err
+}
+
+// Rights decodes the first rights container on the record, returning nil when
+// there is none.
+func (r Record) Rights() (*Rights, error) {
+	rights := new(Rights)
+	found, err := r.decodeAbout(rightsNamespace, rights)
+
+	if !found {
+		return nil, err
+	}
+
+	return rights, err
+}
+
+func (r Record) decodeAbout(namespace string, into interface{}) (bool, error) {
+	containers := make([][]byte, len(r.About))
+
+	for i, about := range r.About {
+		containers[i] = about.Raw
+	}
+
+	return decodeContainer(containers, namespace, into)
+}
+
+// Chain returns this description followed by each one nested within it,
+// nearest origin last.
+func (o OriginDescription) Chain() []OriginDescription {
+	chain := []OriginDescription{}
+
+	for current := &o; current != nil; current = current.OriginDescription {
+		chain = append(chain, *current)
+	}
+
+	return chain
+}
+
+// decodeContainer decodes the first container whose root element belongs to
+// the namespace, reporting whether one was found.
+func decodeContainer(containers [][]byte, namespace string, into interface{}) (bool, error) {
+	for _, raw := range containers {
+		name, err := rootName(raw)
+
+		if err != nil {
+			return false, err
+		}
+
+		if name.Space == namespace {
+			return true, xml.Unmarshal(raw, into)
+		}
+	}
+
+	return false, nil
+}
+
+func rootName(raw []byte) (xml.Name, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(raw))
+
+	for {
+		token, err := decoder.Token()
+
+		if err == io.EOF {
+			return xml.Name{}, errors.New("Container holds no element")
+		}
+
+		if err != nil {
+			return xml.Name{}, err
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package oaipmh
+
+import (
+	"encoding/xml"
+	. "gopkg.in/check.v1"
+)
+
+type aboutSuite struct{}
+
+var _ = Suite(&aboutSuite{})
+
+const recordWithAbout = `<record>
+  <header><identifier>oai:arXiv.org:cs/0112017</identifier></header>
+  <metadata><oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/"></oai_dc:dc></metadata>
+  <about>
+    <provenance xmlns="http://www.openarchives.org/OAI/2.0/provenance">
+      <originDescription harvestDate="2002-02-02T14:10:02Z" altered="true">
+        <baseURL>http://the.oa.org</baseURL>
+        <identifier>oai:r2.org:klik001</identifier>
+        <datestamp>2001-01-01</datestamp>
+        <metadataNamespace>http://www.openarchives.org/OAI/2.0/oai_dc/</metadataNamespace>
+        <originDescription harvestDate="2002-01-01T11:10:01Z" altered="false">
+          <baseURL>http://some.oa.org</baseURL>
+          <identifier>oai:r2.org:klik001</identifier>
+          <datestamp>2001-01-01</datestamp>
+          <metadataNamespace>http://www.openarchives.org/OAI/2.0/oai_dc/</metadataNamespace>
+        </originDescription>
+      </originDescription>
+    </provenance>
+  </about>
+  <about>
+    <rights xmlns="http://www.openarchives.org/OAI/2.0/rights/">
+      <rightsReference ref="http://creativecommons.org/licenses/by/4.0/"/>
+    </rights>
+  </about>
+</record>`
+
+func (s *aboutSuite) TestRecordCapturesEveryAboutContainer(c *C) {
+	record := Record{}
+	err := xml.Unmarshal([]byte(recordWithAbout), &record)
+
+	c.Assert(err, IsNil)
+	c.Assert(record.About, HasLen, 2)
+
+	name, err := record.About[1].Name()
+
+	c.Assert(err, IsNil)
+	c.Assert(name, Equals, xml.Name{Space: rightsNamespace, Local: "rights"})
+}
+
+func (s *aboutSuite) TestProvenanceKeepsOriginChain(c *C) {
+	record := Record{}
+	xml.Unmarshal([]byte(recordWithAbout), &record)
+	provenance, err := record.Provenance()
+
+	c.Assert(err, IsNil)
+	c.Assert(provenance, NotNil)
+
+	chain := provenance.OriginDescription.Chain()
+
+	c.Assert(chain, HasLen, 2)
+	c.Assert(chain[0].BaseURL, Equals, "http://the.oa.org")
+	c.Assert(chain[0].Altered, Equals, true)
+	c.Assert(chain[0].HarvestDate, Equals, Datestamp("2002-02-02T14:10:02Z"))
+	c.Assert(chain[1].BaseURL, Equals, "http://some.oa.org")
+	c.Assert(chain[1].Datestamp, Equals, Datestamp("2001-01-01"))
+	c.Assert(chain[1].OriginDescription, IsNil)
+}
+
+func (s *aboutSuite) TestProvenanceRoundTrips(c *C) {
+	record := Record{}
+	xml.Unmarshal([]byte(recordWithAbout), &record)
+	provenance, _ := record.Provenance()
+
+	wrapped := Provenance{OriginDescription: OriginDescription{
+		HarvestDate:       "2016-03-27T18:20:04Z",
+		BaseURL:           "http://aggregator.example.org/oai",
+		Identifier:        "oai:r2.org:klik001",
+		Datestamp:         "2016-03-27",
+		MetadataNamespace: "http://www.openarchives.org/OAI/2.0/oai_dc/",
+		OriginDescription: &provenance.OriginDescription,
+	}}
+
+	raw, err := xml.Marshal(wrapped)
+
+	c.Assert(err, IsNil)
+
+	decoded, err := Record{About: []About{{Raw: raw}}}.Provenance()
+
+	c.Assert(err, IsNil)
+	c.Assert(decoded.OriginDescription.Chain(), HasLen, 3)
+	c.Assert(decoded.OriginDescription.Chain()[2].BaseURL, Equals, "http://some.oa.org")
+}
+
+func (s *aboutSuite) TestRightsDecodesReference(c *C) {
+	record := Record{}
+	xml.Unmarshal([]byte(recordWithAbout), &record)
+	rights, err := record.Rights()
+
+	c.Assert(err, IsNil)
+	c.Assert(rights.RightsReference, NotNil)
+	c.Assert(rights.RightsReference.Ref, Equals, "http://creativecommons.org/licenses/by/4.0/")
+	c.Assert(rights.RightsDefinition, IsNil)
+}
+
+func (s *aboutSuite) TestMissingContainersAreNil(c *C) {
+	provenance, err := Record{}.Provenance()
+
+	c.Assert(err, IsNil)
+	c.Assert(provenance, IsNil)
+
+	rights, err := Record{}.Rights()
+
+	c.Assert(err, IsNil)
+	c.Assert(rights, IsNil)
+}
@@ -0,0 +1,183 @@
+package oaipmh
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Authenticator attaches credentials to each outgoing request, including
+// retries.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+type BearerToken string
+
+// TokenFunc supplies a bearer token along with the time it expires. A zero
+// expiry means the token is used until the repository rejects it.
+type TokenFunc func(ctx context.Context) (token string, expiry time.Time, err error)
+
+// RefreshingBearerToken caches the token from a TokenFunc, fetching a new one
+// once it expires or after the repository answers 401.
+type RefreshingBearerToken struct {
+	fetch  TokenFunc
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// authenticationError marks a failure to attach credentials, which is not
+// retried.
+type authenticationError struct {
+	err error
+}
+
+// invalidator is implemented by authenticators holding credentials that can
+// be discarded and fetched again after a 401.
+type invalidator interface {
+	Invalidate()
+}
+
+// tokenExpiryMargin renews tokens slightly early so they do not lapse in
+// flight.
+const tokenExpiryMargin = 10 * time.Second
+
+func (e *authenticationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *authenticationError) Unwrap() error {
+	return e.err
+}
+
+func (a BasicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+
+	return nil
+}
+
+func (t BearerToken) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+string(t))
+
+	return nil
+}
+
+func NewRefreshingBearerToken(fetch TokenFunc) *RefreshingBearerToken {
+	return &RefreshingBearerToken{fetch: fetch}
+}
+
+func (t *RefreshingBearerToken) Authenticate(req *http.Request) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token == "" || (!t.expiry.IsZero() && time.Now().Add(tokenExpiryMargin).After(t.expiry)) {
+		token, expiry, err := t.fetch(req.Context())
+
+		if err != nil {
+			return err
+		}
+
+		t.token, t.expiry = token, expiry
+	}
+
+	req.Header.Set("Authorization", "Bearer "+t.token)
+
+	return nil
+}
+
+func (t *RefreshingBearerToken) Invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.token = ""
+}
+
+func WithAuthenticator(authenticator Authenticator) Option {
+	return func(c *Client) error {
+		if authenticator == nil {
+			return errors.New("Authenticator must not be nil")
+		}
+
+		c.authenticator = authenticator
+
+		return nil
+	}
+}
+
+// WithClientCertificate presents the certificate during TLS handshakes. The
+// transport in use is cloned rather than modified, so it must be an
+// *http.Transport.
+func WithClientCertificate(certificate tls.Certificate) Option {
+	return func(c *Client) error {
+		c.certificates = append(c.certificates, certificate)
+
+		return nil
+	}
+}
+
+func (c *Client) applyCertificates() error {
+	if len(c.certificates) == 0 {
+		return nil
+	}
+
+	transport := c.http.Transport
+
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	base, ok := transport.(*http.Transport)
+
+	if !ok {
+		return errors.New("Client certificates require an *http.Transport")
+	}
+
+	cloned := base.Clone()
+
+	if cloned.TLSClientConfig == nil {
+		cloned.TLSClientConfig = &tls.Config{}
+	}
+
+	cloned.TLSClientConfig.Certificates = append(append([]tls.Certificate{}, cloned.TLSClientConfig.Certificates...), c.certificates...)
+	httpClient := *c.http
+	httpClient.Transport = cloned
+	c.http = &httpClient
+
+	return nil
+}
+
+// redactURL hides the password in a URL, leaving anything unparseable as is.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+
+	if err != nil {
+		return raw
+	}
+
+	return u.Redacted()
+}
+
+// redact strips any userinfo from the URL reported by a transport error.
+func redact(err error) error {
+	urlErr, ok := err.(*url.Error)
+
+	if !ok {
+		return err
+	}
+
+	if u, parseErr := url.Parse(urlErr.URL); parseErr == nil && u.User != nil {
+		return &url.Error{Op: urlErr.Op, URL: u.Redacted(), Err: urlErr.Err}
+	}
+
+	return err
+}
@@ -0,0 +1,263 @@
+package oaipmh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// CheckpointKey identifies a list being paged. As with StateKey, BaseURL is
+// held without userinfo. From and Until are formatted as they are sent to the
+// repository, so lists over different date ranges are kept apart.
+type CheckpointKey struct {
+	Verb           string `json:"verb"`
+	BaseURL        string `json:"baseURL"`
+	Set            string `json:"set,omitempty"`
+	MetadataPrefix string `json:"metadataPrefix,omitempty"`
+	From           string `json:"from,omitempty"`
+	Until          string `json:"until,omitempty"`
+}
+
+// Checkpoint is the position of a list harvest after its last complete page.
+// ResponseDate is that of the first page, so an incremental harvest resumed
+// from here still records where the original run began.
+type Checkpoint struct {
+	Token          string    `json:"token"`
+	ExpirationDate Datestamp `json:"expirationDate,omitempty"`
+	Cursor         *int      `json:"cursor,omitempty"`
+	Fetched        int       `json:"fetched"`
+	Pages          int       `json:"pages"`
+	ResponseDate   Datestamp `json:"responseDate,omitempty"`
+}
+
+type CheckpointStore interface {
+	LoadCheckpoint(ctx context.Context, key CheckpointKey) (Checkpoint, bool, error)
+	SaveCheckpoint(ctx context.Context, key CheckpointKey, checkpoint Checkpoint) error
+	ClearCheckpoint(ctx context.Context, key CheckpointKey) error
+}
+
+type MemoryCheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[CheckpointKey]Checkpoint
+}
+
+type FileCheckpointStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+type fileCheckpointEntry struct {
+	CheckpointKey
+	Checkpoint
+}
+
+// IterateOption configures a single iterator.
+type IterateOption func(*pager)
+
+// checkpointing ties a pager to the store its position is saved in.
+type checkpointing struct {
+	ctx     context.Context
+	store   CheckpointStore
+	resolve func() (CheckpointKey, error)
+	key     CheckpointKey
+	loaded  bool
+	resumed bool
+}
+
+// WithCheckpoints saves the iterator's position after every page. A later
+// iterator for the same list picks up from the saved resumption token while
+// it is valid, and otherwise starts the list again from its original
+// arguments, so records from before the interruption may be seen twice.
+func WithCheckpoints(store CheckpointStore) IterateOption {
+	return func(p *pager) {
+		p.checkpoints = &checkpointing{store: store}
+	}
+}
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: map[CheckpointKey]Checkpoint{}}
+}
+
+func (s *MemoryCheckpointStore) LoadCheckpoint(ctx context.Context, key CheckpointKey) (Checkpoint, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checkpoint, ok := s.checkpoints[key]
+
+	return checkpoint, ok, nil
+}
+
+func (s *MemoryCheckpointStore) SaveCheckpoint(ctx context.Context, key CheckpointKey, checkpoint Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoints[key] = checkpoint
+
+	return nil
+}
+
+func (s *MemoryCheckpointStore) ClearCheckpoint(ctx context.Context, key CheckpointKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.checkpoints, key)
+
+	return nil
+}
+
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+func (s *FileCheckpointStore) LoadCheckpoint(ctx context.Context, key CheckpointKey) (Checkpoint, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.read()
+
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+
+	for _, entry := range entries {
+		if entry.CheckpointKey == key {
+			return entry.Checkpoint, true, nil
+		}
+	}
+
+	return Checkpoint{}, false, nil
+}
+
+func (s *FileCheckpointStore) SaveCheckpoint(ctx context.Context, key CheckpointKey, checkpoint Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.read()
+
+	if err != nil {
+		return err
+	}
+
+	kept := []fileCheckpointEntry{{key, checkpoint}}
+
+	for _, entry := range entries {
+		if entry.CheckpointKey != key {
+			kept = append(kept, entry)
+		}
+	}
+
+	return writeJSONFile(s.path, kept)
+}
+
+func (s *FileCheckpointStore) ClearCheckpoint(ctx context.Context, key CheckpointKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.read()
+
+	if err != nil {
+		return err
+	}
+
+	kept := []fileCheckpointEntry{}
+
+	for _, entry := range entries {
+		if entry.CheckpointKey != key {
+			kept = append(kept, entry)
+		}
+	}
+
+	return writeJSONFile(s.path, kept)
+}
+
+func (s *FileCheckpointStore) read() ([]fileCheckpointEntry, error) {
+	entries := []fileCheckpointEntry{}
+	contents, err := ioutil.ReadFile(s.path)
+
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, json.Unmarshal(contents, &entries)
+}
+
+// restore moves a pager that has not yet fetched anything to the saved
+// checkpoint, if there is one.
+func (p *pager) restore() error {
+	if p.checkpoints == nil || p.checkpoints.loaded {
+		return nil
+	}
+
+	p.checkpoints.loaded = true
+	key, err := p.checkpoints.resolve()
+
+	if err != nil {
+		return err
+	}
+
+	p.checkpoints.key = key
+	checkpoint, found, err := p.checkpoints.store.LoadCheckpoint(p.checkpoints.ctx, key)
+
+	if err != nil || !found || checkpoint.Token == "" {
+		return err
+	}
+
+	p.checkpoints.resumed = true
+	p.token = checkpoint.Token
+	p.responseDate = checkpoint.ResponseDate
+	p.last = ResumptionToken{Value: checkpoint.Token, ExpirationDate: checkpoint.ExpirationDate, Cursor: checkpoint.Cursor}
+	p.progress = Progress{Pages: checkpoint.Pages, Fetched: checkpoint.Fetched}
+
+	return nil
+}
+
+// restart abandons a checkpoint the repository no longer accepts and begins
+// the list again, reporting whether it did so.
+func (p *pager) restart(err error) bool {
+	if p.checkpoints == nil || !p.checkpoints.resumed || p.count > 0 {
+		return false
+	}
+
+	if !errors.Is(err, ErrBadResumptionToken) && !errors.Is(err, ErrResumptionTokenExpired) {
+		return false
+	}
+
+	p.checkpoints.resumed = false
+	p.token = p.initial
+	p.responseDate = ""
+	p.last = ResumptionToken{}
+	p.progress = Progress{}
+	p.err = p.checkpoints.store.ClearCheckpoint(p.checkpoints.ctx, p.checkpoints.key)
+
+	return p.err == nil
+}
+
+// checkpoint saves the position reached after a page, clearing it once the
+// list is complete.
+func (p *pager) checkpoint() error {
+	if p.checkpoints == nil {
+		return nil
+	}
+
+	p.checkpoints.resumed = false
+
+	if p.last.Value == "" {
+		return p.checkpoints.store.ClearCheckpoint(p.checkpoints.ctx, p.checkpoints.key)
+	}
+
+	return p.checkpoints.store.SaveCheckpoint(p.checkpoints.ctx, p.checkpoints.key, Checkpoint{
+		Token:          p.last.Value,
+		ExpirationDate: p.last.ExpirationDate,
+		Cursor:         p.last.Cursor,
+		Fetched:        p.progress.Fetched,
+		Pages:          p.progress.Pages,
+		ResponseDate:   p.responseDate,
+	})
+}
@@ -0,0 +1,164 @@
+package oaipmh
+
+import (
+	"context"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type checkpointSuite struct{}
+
+var _ = Suite(&checkpointSuite{})
+
+var checkpointPages = map[string]string{
+	"": `<ListRecords>
+	  <record><header><identifier>a</identifier></header></record>
+	  <record><header><identifier>b</identifier></header></record>
+	  <resumptionToken cursor="0">page2</resumptionToken>
+	</ListRecords>`,
+	"page2": `<ListRecords>
+	  <record><header><identifier>c</identifier></header></record>
+	  <resumptionToken cursor="2"/>
+	</ListRecords>`,
+	"stale": `<error code="badResumptionToken">The token has expired</error>`,
+}
+
+func identifiers(it *RecordIterator) []string {
+	identifiers := []string{}
+
+	for it.Next() {
+		identifiers = append(identifiers, it.Record().Header.Identifier)
+	}
+
+	return identifiers
+}
+
+func (s *checkpointSuite) TestInterruptedHarvestResumesFromCheckpoint(c *C) {
+	server, client, requested := mockPagedClient("resumptionToken", checkpointPages)
+	defer server.Close()
+
+	store := NewMemoryCheckpointStore()
+	key := CheckpointKey{"ListRecords", server.URL, "", "oai_dc", "", ""}
+	first := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"}, WithCheckpoints(store))
+
+	for first.Next() && first.Record().Header.Identifier != "c" {
+	}
+
+	first.Close()
+	checkpoint, found, _ := store.LoadCheckpoint(context.Background(), key)
+
+	c.Assert(found, Equals, true)
+	c.Assert(checkpoint.Token, Equals, "page2")
+	c.Assert(checkpoint.Fetched, Equals, 2)
+	c.Assert(checkpoint.Pages, Equals, 1)
+	c.Assert(checkpoint.ResponseDate, Equals, Datestamp("2016-03-27T18:20:04Z"))
+
+	*requested = []string{}
+	second := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"}, WithCheckpoints(store))
+
+	c.Assert(identifiers(second), DeepEquals, []string{"c"})
+	c.Assert(second.Err(), IsNil)
+	c.Assert(*requested, DeepEquals, []string{"page2"})
+	c.Assert(second.Progress().Pages, Equals, 2)
+	c.Assert(second.Progress().Fetched, Equals, 3)
+
+	_, found, _ = store.LoadCheckpoint(context.Background(), key)
+
+	c.Assert(found, Equals, false)
+}
+
+func (s *checkpointSuite) TestDateRangesKeepSeparateCheckpoints(c *C) {
+	server, client, requested := mockPagedClient("resumptionToken", checkpointPages, WithGranularity(GranularityDay))
+	defer server.Close()
+
+	store := NewMemoryCheckpointStore()
+	earlier := ListOptions{MetadataPrefix: "oai_dc", From: time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)}
+	first := client.IterateRecords(earlier, WithCheckpoints(store))
+
+	for first.Next() && first.Record().Header.Identifier != "c" {
+	}
+
+	first.Close()
+	_, found, _ := store.LoadCheckpoint(context.Background(), CheckpointKey{"ListRecords", server.URL, "", "oai_dc", "2016-01-01", ""})
+
+	c.Assert(found, Equals, true)
+
+	*requested = []string{}
+	later := ListOptions{MetadataPrefix: "oai_dc", From: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	second := client.IterateRecords(later, WithCheckpoints(store))
+
+	c.Assert(identifiers(second), DeepEquals, []string{"a", "b", "c"})
+	c.Assert(*requested, DeepEquals, []string{"", "page2"})
+}
+
+func (s *checkpointSuite) TestRejectedTokenRestartsFromOriginalArguments(c *C) {
+	server, client, requested := mockPagedClient("resumptionToken", checkpointPages)
+	defer server.Close()
+
+	store := NewMemoryCheckpointStore()
+	store.SaveCheckpoint(context.Background(), CheckpointKey{"ListRecords", server.URL, "", "oai_dc", "", ""}, Checkpoint{Token: "stale", Pages: 5, Fetched: 500})
+	it := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"}, WithCheckpoints(store))
+
+	c.Assert(identifiers(it), DeepEquals, []string{"a", "b", "c"})
+	c.Assert(it.Err(), IsNil)
+	c.Assert(*requested, DeepEquals, []string{"stale", "", "page2"})
+	c.Assert(it.Progress().Pages, Equals, 2)
+}
+
+func (s *checkpointSuite) TestExpiredCheckpointIsNotSent(c *C) {
+	server, client, requested := mockPagedClient("resumptionToken", checkpointPages)
+	defer server.Close()
+
+	store := NewMemoryCheckpointStore()
+	store.SaveCheckpoint(context.Background(), CheckpointKey{"ListRecords", server.URL, "", "oai_dc", "", ""}, Checkpoint{Token: "page2", ExpirationDate: "2000-01-01T00:00:00Z", Pages: 1})
+	it := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"}, WithCheckpoints(store))
+
+	c.Assert(identifiers(it), DeepEquals, []string{"a", "b", "c"})
+	c.Assert(*requested, DeepEquals, []string{"", "page2"})
+}
+
+func (s *checkpointSuite) TestFileCheckpointStore(c *C) {
+	path := filepath.Join(c.MkDir(), "checkpoints.json")
+	first := CheckpointKey{"ListRecords", "http://example.org/oai", "", "oai_dc", "", ""}
+	second := CheckpointKey{"ListIdentifiers", "http://example.org/oai", "", "oai_dc", "", ""}
+	cursor := 100
+
+	c.Assert(NewFileCheckpointStore(path).SaveCheckpoint(context.Background(), first, Checkpoint{Token: "one"}), IsNil)
+	c.Assert(NewFileCheckpointStore(path).SaveCheckpoint(context.Background(), second, Checkpoint{Token: "two", Cursor: &cursor}), IsNil)
+	c.Assert(NewFileCheckpointStore(path).ClearCheckpoint(context.Background(), first), IsNil)
+
+	store := NewFileCheckpointStore(path)
+	_, found, err := store.LoadCheckpoint(context.Background(), first)
+
+	c.Assert(err, IsNil)
+	c.Assert(found, Equals, false)
+
+	checkpoint, found, err := store.LoadCheckpoint(context.Background(), second)
+
+	c.Assert(err, IsNil)
+	c.Assert(found, Equals, true)
+	c.Assert(checkpoint.Token, Equals, "two")
+	c.Assert(*checkpoint.Cursor, Equals, 100)
+}
+
+func (s *checkpointSuite) TestCheckpointKeysExcludeCredentials(c *C) {
+	server, _, _ := mockPagedClient("resumptionToken", checkpointPages)
+	defer server.Close()
+
+	path := filepath.Join(c.MkDir(), "checkpoints.json")
+	client, _ := NewClient(strings.Replace(server.URL, "http://", "http://user:secret@", 1))
+	it := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"}, WithCheckpoints(NewFileCheckpointStore(path)))
+
+	for it.Next() && it.Record().Header.Identifier != "c" {
+	}
+
+	it.Close()
+	contents, _ := ioutil.ReadFile(path)
+	_, found, _ := NewFileCheckpointStore(path).LoadCheckpoint(context.Background(), CheckpointKey{"ListRecords", server.URL, "", "oai_dc", "", ""})
+
+	c.Assert(found, Equals, true)
+	c.Assert(strings.Contains(string(contents), "secret"), Equals, false)
+}
@@ -1,6 +1,8 @@
 package oaipmh
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -8,14 +10,33 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"strings"
+	"sync"
 	"time"
 )
 
-const formatISO8601 string = "%04d-%02d-%02dT%02d:%02d:%02dZ"
-
 type Client struct {
-	baseURL string
-	http    *http.Client
+	baseURL   string
+	http      *http.Client
+	transport http.RoundTripper
+	userAgent string
+	from      string
+	timeout   time.Duration
+	retry     RetryPolicy
+	deleted   DeletedRecordHandling
+	post      bool
+	postAbove int
+
+	authenticator Authenticator
+	certificates  []tls.Certificate
+	limiter       *RateLimiter
+
+	mu          sync.RWMutex
+	granularity Granularity
+	configured  bool
+	encodings   []string
+	compressed  bool
+	negotiated  bool
 }
 
 type HTTPResponse struct {
@@ -23,115 +44,317 @@ type HTTPResponse struct {
 	Raw        []byte
 }
 
-func NewClient(baseURL string) (*Client, error) {
-	return &Client{
+func NewClient(baseURL string, options ...Option) (*Client, error) {
+	if err := validateBaseURL(baseURL); err != nil {
+		return nil, err
+	}
+
+	client := &Client{
 		baseURL: baseURL,
 		http:    &http.Client{},
-	}, nil
+		retry:   DefaultRetryPolicy,
+	}
+
+	for _, option := range options {
+		if err := option(client); err != nil {
+			return nil, err
+		}
+	}
+
+	if client.transport != nil {
+		httpClient := *client.http
+		httpClient.Transport = client.transport
+		client.http = &httpClient
+	}
+
+	if err := client.applyCertificates(); err != nil {
+		return nil, err
+	}
+
+	return client, nil
 }
 
 func (c *Client) ListMetadataFormats(options *ListMetadataFormatsOptions) (*ListMetadataFormatsResponse, *HTTPResponse, error) {
+	return c.ListMetadataFormatsContext(context.Background(), options)
+}
+
+func (c *Client) ListMetadataFormatsContext(ctx context.Context, options *ListMetadataFormatsOptions) (*ListMetadataFormatsResponse, *HTTPResponse, error) {
 	params := prepareParameters("ListMetadataFormats", map[string]string{
 		"identifier": options.Identifier,
 	})
 
 	response := new(ListMetadataFormatsResponse)
-	httpResponse, err := c.fetchXML(params, response)
+	httpResponse, err := c.fetchXML(ctx, params, response)
 
 	return response, httpResponse, err
 }
 
 func (c *Client) Identify() (*IdentifyResponse, *HTTPResponse, error) {
+	return c.IdentifyContext(context.Background())
+}
+
+func (c *Client) IdentifyContext(ctx context.Context) (*IdentifyResponse, *HTTPResponse, error) {
 	params := prepareParameters("Identify", map[string]string{})
 	response := new(IdentifyResponse)
-	httpResponse, err := c.fetchXML(params, response)
+	httpResponse, err := c.fetchXML(ctx, params, response)
+
+	if err == nil {
+		c.learn(response.Identify)
+	}
 
 	return response, httpResponse, err
 }
 
 func (c *Client) GetRecord(options *GetRecordOptions, record interface{}) (*GetRecordResponse, *HTTPResponse, error) {
+	return c.GetRecordContext(context.Background(), options, record)
+}
+
+func (c *Client) GetRecordContext(ctx context.Context, options *GetRecordOptions, record interface{}) (*GetRecordResponse, *HTTPResponse, error) {
+	response, httpResponse, err := c.getRecord(ctx, options)
+
+	if err != nil {
+		return response, httpResponse, err
+	}
+
+	return response, httpResponse, unmarshalRecord(response.Record, record)
+}
+
+func (c *Client) getRecord(ctx context.Context, options *GetRecordOptions) (*GetRecordResponse, *HTTPResponse, error) {
 	params := prepareParameters("GetRecord", map[string]string{
 		"identifier":     options.Identifier,
 		"metadataPrefix": options.MetadataPrefix,
 	})
 
 	response := new(GetRecordResponse)
-	httpResponse, err := c.fetchXML(params, response)
+	httpResponse, err := c.fetchXML(ctx, params, response)
+
+	return response, httpResponse, err
+}
+
+func (c *Client) ListRecords(options *ListOptions, records interface{}) (*ListRecordsResponse, *HTTPResponse, error) {
+	return c.ListRecordsContext(context.Background(), options, records)
+}
+
+func (c *Client) ListRecordsContext(ctx context.Context, options *ListOptions, records interface{}) (*ListRecordsResponse, *HTTPResponse, error) {
+	response, httpResponse, err := c.listRecords(ctx, options)
 
 	if err != nil {
 		return response, httpResponse, err
 	}
 
-	return response, httpResponse, unmarshalRecord(response.Record, record)
+	return response, httpResponse, unmarshalRecords(response.Records, records, c.deleted)
 }
 
-func (c *Client) ListRecords(options *ListOptions, records interface{}) (*ListRecordsResponse, *HTTPResponse, error) {
-	params := prepareParameters("ListRecords", map[string]string{
-		"metadataPrefix":  options.MetadataPrefix,
-		"from":            formatDateTime(options.From),
-		"until":           formatDateTime(options.Until),
-		"set":             options.Set,
-		"resumptionToken": options.ResumptionToken,
-	})
-
-	response := new(ListRecordsResponse)
-	httpResponse, err := c.fetchXML(params, response)
+func (c *Client) listRecords(ctx context.Context, options *ListOptions) (*ListRecordsResponse, *HTTPResponse, error) {
+	params, err := c.listParameters(ctx, "ListRecords", options)
 
 	if err != nil {
-		return response, httpResponse, err
+		return new(ListRecordsResponse), &HTTPResponse{}, err
 	}
 
-	return response, httpResponse, unmarshalRecords(response.Records, records)
+	response := new(ListRecordsResponse)
+	httpResponse, err := c.fetchXML(ctx, params, response)
+
+	return response, httpResponse, err
 }
 
 func (c *Client) ListIdentifiers(options *ListOptions) (*ListIdentifiersResponse, *HTTPResponse, error) {
-	params := prepareParameters("ListIdentifiers", map[string]string{
-		"metadataPrefix":  options.MetadataPrefix,
-		"from":            formatDateTime(options.From),
-		"until":           formatDateTime(options.Until),
-		"set":             options.Set,
-		"resumptionToken": options.ResumptionToken,
-	})
+	return c.ListIdentifiersContext(context.Background(), options)
+}
+
+func (c *Client) ListIdentifiersContext(ctx context.Context, options *ListOptions) (*ListIdentifiersResponse, *HTTPResponse, error) {
+	params, err := c.listParameters(ctx, "ListIdentifiers", options)
+
+	if err != nil {
+		return new(ListIdentifiersResponse), &HTTPResponse{}, err
+	}
 
 	response := new(ListIdentifiersResponse)
-	httpResponse, err := c.fetchXML(params, response)
+	httpResponse, err := c.fetchXML(ctx, params, response)
 
 	return response, httpResponse, err
 }
 
 func (c *Client) ListSets(options *ListSetsOptions) (*ListSetsResponse, *HTTPResponse, error) {
+	return c.ListSetsContext(context.Background(), options)
+}
+
+func (c *Client) ListSetsContext(ctx context.Context, options *ListSetsOptions) (*ListSetsResponse, *HTTPResponse, error) {
 	params := prepareParameters("ListSets", map[string]string{
 		"resumptionToken": options.ResumptionToken,
 	})
 
 	response := new(ListSetsResponse)
-	httpResponse, err := c.fetchXML(params, response)
+	httpResponse, err := c.fetchXML(ctx, params, response)
 
 	return response, httpResponse, err
 }
 
-func (c *Client) fetch(params url.Values) (*HTTPResponse, error) {
-	query := params.Encode()
-	path := fmt.Sprintf("%s?%s", c.baseURL, query)
-	res, err := c.http.Get(path)
+func (c *Client) fetch(ctx context.Context, params url.Values) (*HTTPResponse, error) {
+	res, cancel, err := c.open(ctx, params)
 
 	if err != nil {
+		if httpErr, ok := err.(*HTTPError); ok {
+			return &HTTPResponse{httpErr.StatusCode, httpErr.Body}, err
+		}
+
 		return &HTTPResponse{}, err
 	}
 
+	defer cancel()
 	defer res.Body.Close()
 	contents, err := ioutil.ReadAll(res.Body)
-	httpResponse := &HTTPResponse{res.StatusCode, contents}
 
-	if httpResponse.StatusCode >= 400 {
-		err = errors.New("Unsuccessful request")
+	return &HTTPResponse{res.StatusCode, contents}, err
+}
+
+// open returns a successful response with its body unread. The returned
+// CancelFunc must be called once the body has been consumed.
+func (c *Client) open(ctx context.Context, params url.Values) (*http.Response, context.CancelFunc, error) {
+	if params.Get("verb") != "Identify" {
+		c.negotiate(ctx)
+	}
+
+	reauthenticated := false
+
+	for attempt := 1; ; attempt++ {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx, c.host()); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		res, cancel, err := c.openOnce(ctx, params)
+
+		if !reauthenticated && c.reauthenticate(err) {
+			reauthenticated = true
+			attempt--
+			continue
+		}
+
+		if err == nil || attempt >= c.retry.MaxAttempts || !retryable(ctx, err) {
+			return res, cancel, err
+		}
+
+		if err := sleep(ctx, c.retry.delay(attempt, retryAfter(err))); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+func (c *Client) openOnce(ctx context.Context, params url.Values) (*http.Response, context.CancelFunc, error) {
+	cancel := context.CancelFunc(func() {})
+
+	if c.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+	}
+
+	req, err := c.newRequest(ctx, params)
+
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	if c.from != "" {
+		req.Header.Set("From", c.from)
+	}
+
+	if c.authenticator != nil {
+		if err := c.authenticator.Authenticate(req); err != nil {
+			cancel()
+			return nil, nil, &authenticationError{err}
+		}
+	}
+
+	c.mu.RLock()
+	encodings := c.encodings
+	c.mu.RUnlock()
+
+	if len(encodings) > 0 {
+		req.Header.Set("Accept-Encoding", strings.Join(encodings, ", "))
 	}
 
-	return httpResponse, err
+	res, err := c.http.Do(req)
+
+	if err != nil {
+		cancel()
+		return nil, nil, redact(err)
+	}
+
+	if len(encodings) > 0 {
+		decodeResponse(res)
+	}
+
+	if res.StatusCode >= 400 {
+		defer cancel()
+		defer res.Body.Close()
+		contents, _ := ioutil.ReadAll(res.Body)
+
+		return nil, nil, &HTTPError{
+			StatusCode: res.StatusCode,
+			Body:       contents,
+			retryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	return res, cancel, nil
+}
+
+func (c *Client) host() string {
+	u, _ := url.Parse(c.baseURL)
+
+	return u.Host
+}
+
+// reauthenticate discards credentials the repository rejected, reporting
+// whether the request is worth making again with fresh ones.
+func (c *Client) reauthenticate(err error) bool {
+	httpErr, ok := err.(*HTTPError)
+
+	if !ok || httpErr.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+
+	refreshable, ok := c.authenticator.(invalidator)
+
+	if !ok {
+		return false
+	}
+
+	refreshable.Invalidate()
+
+	return true
 }
 
-func (c *Client) fetchXML(params url.Values, into interface{}) (*HTTPResponse, error) {
-	httpResponse, err := c.fetch(params)
+// newRequest builds a GET, or a form-encoded POST when configured to or when
+// the URL would exceed the POST threshold. It is rebuilt for every attempt so
+// a POST body is never reused.
+func (c *Client) newRequest(ctx context.Context, params url.Values) (*http.Request, error) {
+	query := params.Encode()
+	path := fmt.Sprintf("%s?%s", c.baseURL, query)
+
+	if !c.post && (c.postAbove == 0 || len(path) <= c.postAbove) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL, strings.NewReader(query))
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req, nil
+}
+
+func (c *Client) fetchXML(ctx context.Context, params url.Values, into interface{}) (*HTTPResponse, error) {
+	httpResponse, err := c.fetch(ctx, params)
 
 	if err != nil {
 		return httpResponse, err
@@ -140,6 +363,30 @@ func (c *Client) fetchXML(params url.Values, into interface{}) (*HTTPResponse, e
 	return httpResponse, unmarshalResponse(httpResponse.Raw, into)
 }
 
+func validateBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+
+	if err != nil {
+		return fmt.Errorf("Invalid base URL: %v", err)
+	}
+
+	baseURL = u.Redacted()
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("Invalid base URL %q: scheme must be http or https", baseURL)
+	}
+
+	if u.Host == "" {
+		return fmt.Errorf("Invalid base URL %q: missing host", baseURL)
+	}
+
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("Invalid base URL %q: query and fragment are not permitted", baseURL)
+	}
+
+	return nil
+}
+
 func unmarshalResponse(data []byte, into interface{}) error {
 	if err := xml.Unmarshal(data, into); err != nil {
 		return err
@@ -151,24 +398,28 @@ func unmarshalResponse(data []byte, into interface{}) error {
 		return err
 	}
 
-	if !responseError.Error.Empty() {
-		return responseError.Error
-	}
-
-	return nil
+	return responseError.Errors.err()
 }
 
 func unmarshalRecord(record Record, into interface{}) error {
+	if envelope, ok := into.(recordEnvelope); ok {
+		return envelope.setRecord(record)
+	}
+
 	typ := reflect.TypeOf(into).Elem()
 
 	if typ.Kind() != reflect.Struct {
 		return errors.New("Non-struct provided")
 	}
 
+	if record.Header.IsDeleted() {
+		return nil
+	}
+
 	return xml.Unmarshal(record.Metadata.Raw, into)
 }
 
-func unmarshalRecords(records []Record, into interface{}) error {
+func unmarshalRecords(records []Record, into interface{}, deleted DeletedRecordHandling) error {
 	pointer := reflect.ValueOf(into)
 	elem := pointer.Elem()
 
@@ -183,18 +434,110 @@ func unmarshalRecords(records []Record, into interface{}) error {
 	}
 
 	typ := field.Type().Elem()
-	size := len(records)
-	slice := reflect.MakeSlice(reflect.SliceOf(typ), size, size)
+	slice := reflect.MakeSlice(reflect.SliceOf(typ), 0, len(records))
+	errs := []error{}
+
+	_, envelopes := reflect.New(typ).Interface().(recordEnvelope)
 
-	for i, item := range records {
+	for _, item := range records {
 		value := reflect.New(typ)
-		xml.Unmarshal(item.Metadata.Raw, value.Interface())
-		slice.Index(i).Set(value.Elem())
+
+		// Only an envelope carries the header that marks a tombstone as
+		// deleted, so other types never receive one.
+		if item.Header.IsDeleted() && (deleted == SkipDeletedRecords || !envelopes) {
+			continue
+		}
+
+		if envelopes {
+			if err := value.Interface().(recordEnvelope).setRecord(item); err != nil {
+				errs = append(errs, err)
+			}
+		} else if err := xml.Unmarshal(item.Metadata.Raw, value.Interface()); err != nil {
+			errs = append(errs, &RecordError{Identifier: item.Header.Identifier, Err: err})
+		}
+
+		slice = reflect.Append(slice, value.Elem())
 	}
 
 	field.Set(slice)
 
-	return nil
+	return errors.Join(errs...)
+}
+
+func (c *Client) listParameters(ctx context.Context, verb string, options *ListOptions) (url.Values, error) {
+	granularity := GranularitySecond
+
+	if !options.From.IsZero() || !options.Until.IsZero() {
+		var err error
+
+		if granularity, err = c.repositoryGranularity(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return prepareParameters(verb, map[string]string{
+		"metadataPrefix":  options.MetadataPrefix,
+		"from":            formatDateTime(options.From, granularity),
+		"until":           formatDateTime(options.Until, granularity),
+		"set":             options.Set,
+		"resumptionToken": options.ResumptionToken,
+	}), nil
+}
+
+// repositoryGranularity returns the granularity advertised by the repository,
+// issuing an Identify request the first time it is needed.
+func (c *Client) repositoryGranularity(ctx context.Context) (Granularity, error) {
+	c.mu.RLock()
+	granularity := c.granularity
+	c.mu.RUnlock()
+
+	if granularity != "" {
+		return granularity, nil
+	}
+
+	if _, _, err := c.IdentifyContext(ctx); err != nil {
+		return "", err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.granularity, nil
+}
+
+// negotiate issues an Identify ahead of the first other request, so that the
+// compression the repository supports is offered from then on. A failed
+// Identify only means that compression is not offered.
+func (c *Client) negotiate(ctx context.Context) {
+	c.mu.Lock()
+	negotiated := c.negotiated
+	c.negotiated = true
+	c.mu.Unlock()
+
+	if !negotiated {
+		c.IdentifyContext(ctx)
+	}
+}
+
+func (c *Client) learn(identify Identify) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.negotiated = true
+
+	if !c.compressed {
+		c.encodings = acceptedEncodings(identify.Compressions)
+	}
+
+	if c.configured {
+		return
+	}
+
+	c.granularity = identify.Granularity
+
+	if c.granularity != GranularityDay {
+		c.granularity = GranularitySecond
+	}
 }
 
 func prepareParameters(verb string, options map[string]string) url.Values {
@@ -210,10 +553,10 @@ func prepareParameters(verb string, options map[string]string) url.Values {
 	return params
 }
 
-func formatDateTime(t time.Time) string {
+func formatDateTime(t time.Time, granularity Granularity) string {
 	if t.IsZero() {
 		return ""
 	}
 
-	return fmt.Sprintf(formatISO8601, t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second())
+	return granularity.Format(t)
 }
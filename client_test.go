@@ -1,12 +1,15 @@
 package oaipmh
 
 import (
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	. "gopkg.in/check.v1"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -28,12 +31,7 @@ func mockClient(code int, body string) (*httptest.Server, *Client) {
 		},
 	}
 
-	http := &http.Client{Transport: tr}
-
-	client := &Client{
-		baseURL: server.URL,
-		http:    http,
-	}
+	client, _ := NewClient(server.URL, WithTransport(tr))
 
 	return server, client
 }
@@ -151,6 +149,7 @@ func (s *clientSuite) TestIdentify(c *C) {
 	server, client := mockClient(200, raw)
 	defer server.Close()
 	identity, _, err := client.Identify()
+	description := raw[strings.Index(raw, "<description>")+len("<description>") : strings.Index(raw, "</description>")]
 
 	expectedIdentity := &IdentifyResponse{
 		XMLName: xml.Name{Space: "http://www.openarchives.org/OAI/2.0/", Local: "OAI-PMH"},
@@ -168,8 +167,9 @@ func (s *clientSuite) TestIdentify(c *C) {
 			EarliestDatestamp: "2011-09-23T08:52:33Z",
 			DeletedRecord:     "persistent",
 			Granularity:       "YYYY-MM-DDThh:mm:ssZ",
-			AdminEmail:        "cjg@ecs.soton.ac.uk",
-			Compression:       "gzip",
+			AdminEmails:       []string{"cjg@ecs.soton.ac.uk"},
+			Compressions:      []string{"gzip"},
+			Descriptions:      []Description{{Raw: []byte(description)}},
 		},
 	}
 
@@ -256,6 +256,7 @@ func (s *clientSuite) TestGetRecord(c *C) {
 			BaseURL: "http://eprints.ecs.soton.ac.uk/cgi/oai2",
 			Verb:    "GetRecord",
 		},
+		ResponseDate: "2016-03-26T19:18:07Z",
 		Record: Record{
 			XMLName: xml.Name{Space: "http://www.openarchives.org/OAI/2.0/", Local: "record"},
 			Header: RecordHeader{
@@ -339,7 +340,8 @@ func (s *clientSuite) TestGetRecordWithErrorResponse(c *C) {
 			BaseURL: "http://eprints.ecs.soton.ac.uk/cgi/oai2",
 			Verb:    "",
 		},
-		Record: Record{},
+		ResponseDate: "2016-03-27T17:54:02Z",
+		Record:       Record{},
 	}
 
 	expectedMetadata := &DublinCoreRecord{}
@@ -394,6 +396,7 @@ func (s *clientSuite) TestListRecords(c *C) {
 			BaseURL: "http://eprints.ecs.soton.ac.uk/cgi/oai2",
 			Verb:    "ListRecords",
 		},
+		ResponseDate: "2016-03-27T18:20:04Z",
 		Records: []Record{
 			Record{
 				XMLName: xml.Name{Space: "http://www.openarchives.org/OAI/2.0/", Local: "record"},
@@ -481,6 +484,7 @@ func (s *clientSuite) TestListRecordsWithErrorResponse(c *C) {
 			BaseURL: "http://eprints.ecs.soton.ac.uk/cgi/oai2",
 			Verb:    "",
 		},
+		ResponseDate:    "2016-03-27T18:37:10Z",
 		Records:         []Record(nil),
 		ResumptionToken: ResumptionToken{},
 	}
@@ -523,6 +527,7 @@ func (s *clientSuite) TestListIdentifiers(c *C) {
 			BaseURL: "http://eprints.ecs.soton.ac.uk/cgi/oai2",
 			Verb:    "ListIdentifiers",
 		},
+		ResponseDate: "2016-04-03T12:10:55Z",
 		Headers: []RecordHeader{
 			RecordHeader{
 				XMLName:    xml.Name{Space: "http://www.openarchives.org/OAI/2.0/", Local: "header"},
@@ -568,6 +573,7 @@ func (s *clientSuite) TestListIdentifiersWithErrorResponse(c *C) {
 			BaseURL: "http://eprints.ecs.soton.ac.uk/cgi/oai2",
 			Verb:    "",
 		},
+		ResponseDate:    "2016-04-03T12:27:41Z",
 		Headers:         []RecordHeader(nil),
 		ResumptionToken: ResumptionToken{},
 	}
@@ -604,6 +610,7 @@ func (s *clientSuite) TestListSets(c *C) {
 			BaseURL: "http://eprints.ecs.soton.ac.uk/cgi/oai2",
 			Verb:    "ListSets",
 		},
+		ResponseDate: "2016-04-06T20:58:20Z",
 		Sets: []Set{
 			Set{
 				XMLName: xml.Name{Space: "http://www.openarchives.org/OAI/2.0/", Local: "set"},
@@ -644,6 +651,7 @@ func (s *clientSuite) TestListSetsWithErrorResponse(c *C) {
 			BaseURL: "http://eprints.ecs.soton.ac.uk/cgi/oai2",
 			Verb:    "",
 		},
+		ResponseDate:    "2016-04-06T21:14:28Z",
 		Sets:            []Set(nil),
 		ResumptionToken: ResumptionToken{},
 	}
@@ -651,3 +659,132 @@ func (s *clientSuite) TestListSetsWithErrorResponse(c *C) {
 	c.Assert(err, NotNil)
 	c.Assert(sets, DeepEquals, expectedSets)
 }
+
+func (s *clientSuite) TestContextCancellationStopsInFlightRequest(c *C) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, _ := NewClient(server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	started := time.Now()
+	_, _, err := client.IdentifyContext(ctx)
+
+	c.Assert(errors.Is(err, context.Canceled), Equals, true)
+	c.Assert(time.Since(started) < time.Second, Equals, true)
+}
+
+func (s *clientSuite) TestContextDeadlineAppliesToEveryVerb(c *C) {
+	server, client := mockClient(200, "")
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := client.ListMetadataFormatsContext(ctx, &ListMetadataFormatsOptions{})
+	c.Assert(errors.Is(err, context.Canceled), Equals, true)
+
+	_, _, err = client.GetRecordContext(ctx, &GetRecordOptions{}, new(DublinCoreRecord))
+	c.Assert(errors.Is(err, context.Canceled), Equals, true)
+
+	_, _, err = client.ListRecordsContext(ctx, &ListOptions{}, new(DublinCoreRecords))
+	c.Assert(errors.Is(err, context.Canceled), Equals, true)
+
+	_, _, err = client.ListIdentifiersContext(ctx, &ListOptions{})
+	c.Assert(errors.Is(err, context.Canceled), Equals, true)
+
+	_, _, err = client.ListSetsContext(ctx, &ListSetsOptions{})
+	c.Assert(errors.Is(err, context.Canceled), Equals, true)
+}
+
+func mockGranularityClient(granularity string, options ...Option) (*httptest.Server, *Client, *[]url.Values) {
+	var queries []url.Values
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+
+		if r.URL.Query().Get("verb") == "Identify" {
+			fmt.Fprintf(w, pageTemplate, "<Identify><granularity>"+granularity+"</granularity></Identify>")
+		} else {
+			fmt.Fprintf(w, pageTemplate, "<ListIdentifiers></ListIdentifiers>")
+		}
+	}))
+
+	client, _ := NewClient(server.URL, options...)
+
+	return server, client, &queries
+}
+
+func (s *clientSuite) TestListDatesUseRepositoryGranularity(c *C) {
+	server, client, queries := mockGranularityClient("YYYY-MM-DD")
+	defer server.Close()
+
+	from := time.Date(2016, 3, 28, 0, 30, 0, 0, time.FixedZone("BST", 3600))
+	_, _, err := client.ListIdentifiers(&ListOptions{MetadataPrefix: "oai_dc", From: from})
+	c.Assert(err, IsNil)
+	_, _, err = client.ListIdentifiers(&ListOptions{MetadataPrefix: "oai_dc", Until: from})
+	c.Assert(err, IsNil)
+
+	c.Assert(*queries, HasLen, 3)
+	c.Assert((*queries)[0].Get("verb"), Equals, "Identify")
+	c.Assert((*queries)[1].Get("from"), Equals, "2016-03-27")
+	c.Assert((*queries)[2].Get("until"), Equals, "2016-03-27")
+}
+
+func (s *clientSuite) TestListDatesAreNormalisedToUTC(c *C) {
+	server, client, queries := mockGranularityClient("YYYY-MM-DDThh:mm:ssZ")
+	defer server.Close()
+
+	from := time.Date(2016, 3, 28, 0, 30, 0, 0, time.FixedZone("BST", 3600))
+	client.ListIdentifiers(&ListOptions{MetadataPrefix: "oai_dc", From: from})
+
+	c.Assert((*queries)[1].Get("from"), Equals, "2016-03-27T23:30:00Z")
+}
+
+func (s *clientSuite) TestConfiguredGranularitySkipsIdentify(c *C) {
+	server, client, queries := mockGranularityClient("YYYY-MM-DDThh:mm:ssZ", WithGranularity(GranularityDay), WithCompression())
+	defer server.Close()
+
+	client.ListIdentifiers(&ListOptions{MetadataPrefix: "oai_dc", From: time.Date(2016, 3, 28, 12, 0, 0, 0, time.UTC)})
+
+	c.Assert(*queries, HasLen, 1)
+	c.Assert((*queries)[0].Get("from"), Equals, "2016-03-28")
+}
+
+func (s *clientSuite) TestConfiguredGranularitySurvivesIdentify(c *C) {
+	server, client, queries := mockGranularityClient("YYYY-MM-DDThh:mm:ssZ", WithGranularity(GranularityDay))
+	defer server.Close()
+
+	client.Identify()
+	client.ListIdentifiers(&ListOptions{MetadataPrefix: "oai_dc", From: time.Date(2016, 3, 28, 12, 0, 0, 0, time.UTC)})
+
+	c.Assert((*queries)[1].Get("from"), Equals, "2016-03-28")
+}
+
+func (s *clientSuite) TestListsWithoutDatesSkipIdentify(c *C) {
+	server, client, queries := mockGranularityClient("YYYY-MM-DD", WithCompression())
+	defer server.Close()
+
+	client.ListIdentifiers(&ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(*queries, HasLen, 1)
+}
+
+func (s *clientSuite) TestParsedDatestamps(c *C) {
+	server, client, _ := mockGranularityClient("YYYY-MM-DD")
+	defer server.Close()
+
+	identity, _, err := client.Identify()
+	c.Assert(err, IsNil)
+	responseDate, err := identity.ResponseDate.Time()
+
+	c.Assert(err, IsNil)
+	c.Assert(responseDate, Equals, time.Date(2016, 3, 27, 18, 20, 4, 0, time.UTC))
+	c.Assert(identity.Identify.Granularity, Equals, GranularityDay)
+}
@@ -0,0 +1,107 @@
+package oaipmh
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var supportedEncodings = map[string]bool{"gzip": true, "deflate": true}
+
+// decodingBody decompresses a response body on first read, so that an empty
+// error body with a Content-Encoding header does not fail up front.
+type decodingBody struct {
+	body     io.ReadCloser
+	encoding string
+	reader   io.Reader
+	closer   io.Closer
+}
+
+func acceptedEncodings(compressions []string) []string {
+	encodings := []string{}
+
+	for _, compression := range compressions {
+		compression = strings.ToLower(strings.TrimSpace(compression))
+
+		if supportedEncodings[compression] {
+			encodings = append(encodings, compression)
+		}
+	}
+
+	return encodings
+}
+
+// decodeResponse swaps in a decompressing body where the response was encoded
+// with one of the encodings the client asked for itself.
+func decodeResponse(res *http.Response) {
+	encoding := strings.ToLower(strings.TrimSpace(res.Header.Get("Content-Encoding")))
+
+	if !supportedEncodings[encoding] {
+		return
+	}
+
+	res.Body = &decodingBody{body: res.Body, encoding: encoding}
+	res.Header.Del("Content-Encoding")
+	res.Header.Del("Content-Length")
+	res.ContentLength = -1
+	res.Uncompressed = true
+}
+
+func (b *decodingBody) Read(p []byte) (int, error) {
+	if b.reader == nil {
+		if err := b.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	return b.reader.Read(p)
+}
+
+func (b *decodingBody) Close() error {
+	if b.closer != nil {
+		b.closer.Close()
+	}
+
+	return b.body.Close()
+}
+
+func (b *decodingBody) open() error {
+	buffered := bufio.NewReader(b.body)
+
+	if b.encoding == "gzip" {
+		reader, err := gzip.NewReader(buffered)
+
+		if err != nil {
+			return err
+		}
+
+		b.reader, b.closer = reader, reader
+
+		return nil
+	}
+
+	// Deflate should be zlib wrapped, but enough servers send a raw stream that
+	// both are accepted.
+	header, _ := buffered.Peek(2)
+
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		reader, err := zlib.NewReader(buffered)
+
+		if err != nil {
+			return err
+		}
+
+		b.reader, b.closer = reader, reader
+
+		return nil
+	}
+
+	reader := flate.NewReader(buffered)
+	b.reader, b.closer = reader, reader
+
+	return nil
+}
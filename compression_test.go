@@ -0,0 +1,132 @@
+package oaipmh
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	. "gopkg.in/check.v1"
+	"io"
+	"net/http"
+	"net/http/httptest"
+)
+
+type compressionSuite struct{}
+
+var _ = Suite(&compressionSuite{})
+
+// mockCompressingClient advertises the given compressions from Identify. Its
+// transport records the Accept-Encoding sent and encodes every other response
+// with the writer for the requested encoding.
+func mockCompressingClient(compressions string, encode func(io.Writer) io.WriteCloser, encoding string, options ...Option) (*httptest.Server, *Client, *[]string) {
+	accepted := []string{}
+
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		accepted = append(accepted, req.Header.Get("Accept-Encoding"))
+		res, err := http.DefaultTransport.RoundTrip(req)
+
+		if err != nil || req.URL.Query().Get("verb") == "Identify" {
+			return res, err
+		}
+
+		defer res.Body.Close()
+		buffer := new(bytes.Buffer)
+		writer := encode(buffer)
+		io.Copy(writer, res.Body)
+		writer.Close()
+
+		res.Header.Set("Content-Encoding", encoding)
+		res.Body = io.NopCloser(buffer)
+
+		return res, nil
+	})
+
+	server, client, _ := mockPagedClient("verb", map[string]string{
+		"Identify":        `<Identify><granularity>YYYY-MM-DD</granularity>` + compressions + `</Identify>`,
+		"ListIdentifiers": `<ListIdentifiers><header><identifier>a</identifier></header></ListIdentifiers>`,
+	}, append([]Option{WithTransport(transport)}, options...)...)
+
+	return server, client, &accepted
+}
+
+func (s *compressionSuite) assertDecodes(c *C, compressions string, encode func(io.Writer) io.WriteCloser, encoding string, accept string) {
+	server, client, accepted := mockCompressingClient(compressions, encode, encoding)
+	defer server.Close()
+
+	_, _, err := client.Identify()
+
+	c.Assert(err, IsNil)
+
+	response, _, err := client.ListIdentifiers(&ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(err, IsNil)
+	c.Assert(response.Headers, HasLen, 1)
+	c.Assert(response.Headers[0].Identifier, Equals, "a")
+	c.Assert((*accepted)[1], Equals, accept)
+}
+
+func (s *compressionSuite) TestGzipIsDecoded(c *C) {
+	s.assertDecodes(c, `<compression>gzip</compression><compression>deflate</compression>`, func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	}, "gzip", "gzip, deflate")
+}
+
+func (s *compressionSuite) TestZlibDeflateIsDecoded(c *C) {
+	s.assertDecodes(c, `<compression>deflate</compression>`, func(w io.Writer) io.WriteCloser {
+		return zlib.NewWriter(w)
+	}, "deflate", "deflate")
+}
+
+func (s *compressionSuite) TestRawDeflateIsDecoded(c *C) {
+	s.assertDecodes(c, `<compression>deflate</compression>`, func(w io.Writer) io.WriteCloser {
+		writer, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return writer
+	}, "deflate", "deflate")
+}
+
+func (s *compressionSuite) TestIteratorsNegotiateCompression(c *C) {
+	server, client, accepted := mockCompressingClient(`<compression>gzip</compression>`, func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	}, "gzip")
+	defer server.Close()
+
+	iterator := client.IterateIdentifiers(ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(iterator.Next(), Equals, true)
+	c.Assert(iterator.Header().Identifier, Equals, "a")
+	c.Assert(iterator.Next(), Equals, false)
+	c.Assert(iterator.Err(), IsNil)
+	c.Assert(*accepted, DeepEquals, []string{"", "gzip"})
+}
+
+func (s *compressionSuite) TestConfiguredCompressionSkipsIdentify(c *C) {
+	server, client, accepted := mockCompressingClient("", func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	}, "gzip", WithCompression("gzip"))
+	defer server.Close()
+
+	response, _, err := client.ListIdentifiers(&ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(err, IsNil)
+	c.Assert(response.Headers, HasLen, 1)
+	c.Assert(*accepted, DeepEquals, []string{"gzip"})
+}
+
+func (s *compressionSuite) TestUnsupportedCompressionsAreNotAdvertised(c *C) {
+	c.Assert(acceptedEncodings([]string{"compress", " GZIP ", "identity"}), DeepEquals, []string{"gzip"})
+}
+
+func (s *compressionSuite) TestEmptyEncodedErrorBodyIsTolerated(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(server.URL, WithRetryPolicy(NoRetryPolicy))
+	client.learn(Identify{Compressions: []string{"gzip"}})
+	_, httpResponse, err := client.ListIdentifiers(&ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(err, FitsTypeOf, &HTTPError{})
+	c.Assert(httpResponse.StatusCode, Equals, http.StatusServiceUnavailable)
+}
@@ -0,0 +1,47 @@
+package oaipmh
+
+import (
+	"strings"
+	"time"
+)
+
+type Datestamp string
+
+type Granularity string
+
+const (
+	GranularityDay    Granularity = "YYYY-MM-DD"
+	GranularitySecond Granularity = "YYYY-MM-DDThh:mm:ssZ"
+)
+
+const (
+	datestampDayLayout    = "2006-01-02"
+	datestampSecondLayout = "2006-01-02T15:04:05Z"
+)
+
+// Time parses both day and second granularity datestamps, returning UTC.
+func (d Datestamp) Time() (time.Time, error) {
+	value := strings.TrimSpace(string(d))
+
+	if len(value) == len(datestampDayLayout) {
+		return time.Parse(datestampDayLayout, value)
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+
+	return t.UTC(), err
+}
+
+func (d Datestamp) IsZero() bool {
+	return strings.TrimSpace(string(d)) == ""
+}
+
+// Format renders t in UTC at the given granularity, defaulting to seconds for
+// unrecognised values.
+func (g Granularity) Format(t time.Time) string {
+	if g == GranularityDay {
+		return t.UTC().Format(datestampDayLayout)
+	}
+
+	return t.UTC().Format(datestampSecondLayout)
+}
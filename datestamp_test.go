@@ -0,0 +1,53 @@
+package oaipmh
+
+import (
+	. "gopkg.in/check.v1"
+	"time"
+)
+
+type datestampSuite struct{}
+
+var _ = Suite(&datestampSuite{})
+
+func (s *datestampSuite) TestTimeParsesSecondGranularity(c *C) {
+	t, err := Datestamp("2011-09-23T10:22:12Z").Time()
+
+	c.Assert(err, IsNil)
+	c.Assert(t, Equals, time.Date(2011, 9, 23, 10, 22, 12, 0, time.UTC))
+}
+
+func (s *datestampSuite) TestTimeParsesDayGranularity(c *C) {
+	t, err := Datestamp("2011-09-23").Time()
+
+	c.Assert(err, IsNil)
+	c.Assert(t, Equals, time.Date(2011, 9, 23, 0, 0, 0, 0, time.UTC))
+}
+
+func (s *datestampSuite) TestTimeRejectsMalformedValues(c *C) {
+	_, err := Datestamp("23/09/2011").Time()
+
+	c.Assert(err, NotNil)
+}
+
+func (s *datestampSuite) TestIsZero(c *C) {
+	c.Assert(Datestamp("").IsZero(), Equals, true)
+	c.Assert(Datestamp(" ").IsZero(), Equals, true)
+	c.Assert(Datestamp("2011-09-23").IsZero(), Equals, false)
+}
+
+func (s *datestampSuite) TestGranularityFormatsInUTC(c *C) {
+	t := time.Date(2016, 3, 28, 1, 30, 0, 0, time.FixedZone("BST", 3600))
+
+	c.Assert(GranularitySecond.Format(t), Equals, "2016-03-28T00:30:00Z")
+	c.Assert(GranularityDay.Format(t), Equals, "2016-03-28")
+	c.Assert(GranularityDay.Format(t.Add(-time.Hour)), Equals, "2016-03-27")
+	c.Assert(Granularity("").Format(t), Equals, "2016-03-28T00:30:00Z")
+}
+
+func (s *datestampSuite) TestFormattedDatestampsRoundTrip(c *C) {
+	t := time.Date(2016, 3, 28, 0, 30, 0, 0, time.UTC)
+	parsed, err := Datestamp(GranularitySecond.Format(t)).Time()
+
+	c.Assert(err, IsNil)
+	c.Assert(parsed, Equals, t)
+}
@@ -0,0 +1,109 @@
+package oaipmh
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+func (d Description) Name() (xml.Name, error) {
+	return rootName(d.Raw)
+}
+
+func (d Description) Decode(into interface{}) error {
+	return xml.Unmarshal(d.Raw, into)
+}
+
+func (i Identify) OAIIdentifier() (*OAIIdentifier, error) {
+	identifier := new(OAIIdentifier)
+	found, err := i.decodeDescription(oaiIdentifierNamespace, identifier)
+
+	if !found {
+		return nil, err
+	}
+
+	return identifier, err
+}
+
+func (i Identify) EPrints() (*EPrints, error) {
+	eprints := new(EPrints)
+	found, err := i.decodeDescription(eprintsNamespace, eprints)
+
+	if !found {
+		return nil, err
+	}
+
+	return eprints, err
+}
+
+func (i Identify) Friends() (*Friends, error) {
+	friends := new(Friends)
+	found, err := i.decodeDescription(friendsNamespace, friends)
+
+	if !found {
+		return nil, err
+	}
+
+	return friends, err
+}
+
+func (i Identify) Branding() (*Branding, error) {
+	branding := new(Branding)
+	found, err := i.decodeDescription(brandingNamespace, branding)
+
+	if !found {
+		return nil, err
+	}
+
+	return branding, err
+}
+
+// FriendBaseURLs lists the distinct base URLs across every friends
+// description, leaving out the repository's own.
+func (i Identify) FriendBaseURLs() ([]string, error) {
+	seen := map[string]bool{strings.TrimSpace(i.BaseURL): true}
+	baseURLs := []string{}
+
+	for _, description := range i.Descriptions {
+		name, err := description.Name()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if name.Space != friendsNamespace {
+			continue
+		}
+
+		friends := new(Friends)
+
+		if err := description.Decode(friends); err != nil {
+			return nil, err
+		}
+
+		for _, baseURL := range friends.BaseURLs {
+			baseURL = strings.TrimSpace(baseURL)
+
+			if baseURL != "" && !seen[baseURL] {
+				seen[baseURL] = true
+				baseURLs = append(baseURLs, baseURL)
+			}
+		}
+	}
+
+	return baseURLs, nil
+}
+
+// Identifier builds an identifier for a local item following the scheme.
+func (o OAIIdentifier) Identifier(local string) string {
+	return strings.Join([]string{o.Scheme, o.RepositoryIdentifier, local}, o.Delimiter)
+}
+
+func (i Identify) decodeDescription(namespace string, into interface{}) (bool, error) {
+	containers := make([][]byte, len(i.Descriptions))
+
+	for j, description := range i.Descriptions {
+		containers[j] = description.Raw
+	}
+
+	return decodeContainer(containers, namespace, into)
+}
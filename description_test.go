@@ -0,0 +1,133 @@
+package oaipmh
+
+import (
+	"encoding/xml"
+	. "gopkg.in/check.v1"
+)
+
+type descriptionSuite struct{}
+
+var _ = Suite(&descriptionSuite{})
+
+const identifyWithDescriptions = `<Identify xmlns="http://www.openarchives.org/OAI/2.0/">
+  <repositoryName>Example Repository</repositoryName>
+  <baseURL>http://www.example.org/oai</baseURL>
+  <adminEmail>one@example.org</adminEmail>
+  <adminEmail>two@example.org</adminEmail>
+  <compression>gzip</compression>
+  <compression>deflate</compression>
+  <description>
+    <oai-identifier xmlns="http://www.openarchives.org/OAI/2.0/oai-identifier">
+      <scheme>oai</scheme>
+      <repositoryIdentifier>example.org</repositoryIdentifier>
+      <delimiter>:</delimiter>
+      <sampleIdentifier>oai:example.org:1234</sampleIdentifier>
+    </oai-identifier>
+  </description>
+  <description>
+    <eprints xmlns="http://www.openarchives.org/OAI/1.1/eprints">
+      <content><URL>http://www.example.org/content.html</URL></content>
+      <metadataPolicy><text>Metadata may be reused freely</text></metadataPolicy>
+      <dataPolicy><URL>http://www.example.org/data.html</URL></dataPolicy>
+      <comment>First comment</comment>
+      <comment>Second comment</comment>
+    </eprints>
+  </description>
+  <description>
+    <friends xmlns="http://www.openarchives.org/OAI/2.0/friends/">
+      <baseURL>http://one.example.org/oai</baseURL>
+      <baseURL>http://www.example.org/oai</baseURL>
+    </friends>
+  </description>
+  <description>
+    <friends xmlns="http://www.openarchives.org/OAI/2.0/friends/">
+      <baseURL>http://one.example.org/oai</baseURL>
+      <baseURL> http://two.example.org/oai </baseURL>
+    </friends>
+  </description>
+  <description>
+    <branding xmlns="http://www.openarchives.org/OAI/2.0/branding/">
+      <collectionIcon>
+        <url>http://www.example.org/icon.png</url>
+        <link>http://www.example.org/</link>
+        <title>Example</title>
+        <width>88</width>
+        <height>31</height>
+      </collectionIcon>
+      <metadataRendering metadataNamespace="http://www.openarchives.org/OAI/2.0/oai_dc/" mimeType="text/xsl">http://www.example.org/dc.xsl</metadataRendering>
+    </branding>
+  </description>
+</Identify>`
+
+func (s *descriptionSuite) identify(c *C) Identify {
+	identify := Identify{}
+
+	c.Assert(xml.Unmarshal([]byte(identifyWithDescriptions), &identify), IsNil)
+
+	return identify
+}
+
+func (s *descriptionSuite) TestMultipleValuesAreCaptured(c *C) {
+	identify := s.identify(c)
+
+	c.Assert(identify.AdminEmails, DeepEquals, []string{"one@example.org", "two@example.org"})
+	c.Assert(identify.Compressions, DeepEquals, []string{"gzip", "deflate"})
+	c.Assert(identify.Descriptions, HasLen, 5)
+}
+
+func (s *descriptionSuite) TestOAIIdentifier(c *C) {
+	identifier, err := s.identify(c).OAIIdentifier()
+
+	c.Assert(err, IsNil)
+	c.Assert(identifier.RepositoryIdentifier, Equals, "example.org")
+	c.Assert(identifier.SampleIdentifier, Equals, "oai:example.org:1234")
+	c.Assert(identifier.Identifier("42"), Equals, "oai:example.org:42")
+}
+
+func (s *descriptionSuite) TestEPrints(c *C) {
+	eprints, err := s.identify(c).EPrints()
+
+	c.Assert(err, IsNil)
+	c.Assert(eprints.Content.URL, Equals, "http://www.example.org/content.html")
+	c.Assert(eprints.MetadataPolicy.Text, Equals, "Metadata may be reused freely")
+	c.Assert(eprints.DataPolicy.URL, Equals, "http://www.example.org/data.html")
+	c.Assert(eprints.SubmissionPolicy, IsNil)
+	c.Assert(eprints.Comments, DeepEquals, []string{"First comment", "Second comment"})
+}
+
+func (s *descriptionSuite) TestBranding(c *C) {
+	branding, err := s.identify(c).Branding()
+
+	c.Assert(err, IsNil)
+	c.Assert(*branding.CollectionIcon, Equals, CollectionIcon{
+		URL:    "http://www.example.org/icon.png",
+		Link:   "http://www.example.org/",
+		Title:  "Example",
+		Width:  88,
+		Height: 31,
+	})
+	c.Assert(branding.MetadataRenderings, DeepEquals, []MetadataRendering{{
+		MetadataNamespace: "http://www.openarchives.org/OAI/2.0/oai_dc/",
+		MimeType:          "text/xsl",
+		URL:               "http://www.example.org/dc.xsl",
+	}})
+}
+
+func (s *descriptionSuite) TestFriendBaseURLsAreDistinctAndExcludeSelf(c *C) {
+	baseURLs, err := s.identify(c).FriendBaseURLs()
+
+	c.Assert(err, IsNil)
+	c.Assert(baseURLs, DeepEquals, []string{"http://one.example.org/oai", "http://two.example.org/oai"})
+}
+
+func (s *descriptionSuite) TestMissingDescriptionsAreNil(c *C) {
+	friends, err := Identify{}.Friends()
+
+	c.Assert(err, IsNil)
+	c.Assert(friends, IsNil)
+
+	baseURLs, err := Identify{}.FriendBaseURLs()
+
+	c.Assert(err, IsNil)
+	c.Assert(baseURLs, HasLen, 0)
+}
@@ -1,9 +1,43 @@
 package oaipmh
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 )
 
+type ErrorCode string
+
+const (
+	ErrBadArgument             ErrorCode = "badArgument"
+	ErrBadResumptionToken      ErrorCode = "badResumptionToken"
+	ErrBadVerb                 ErrorCode = "badVerb"
+	ErrCannotDisseminateFormat ErrorCode = "cannotDisseminateFormat"
+	ErrIDDoesNotExist          ErrorCode = "idDoesNotExist"
+	ErrNoRecordsMatch          ErrorCode = "noRecordsMatch"
+	ErrNoMetadataFormats       ErrorCode = "noMetadataFormats"
+	ErrNoSetHierarchy          ErrorCode = "noSetHierarchy"
+)
+
+var ErrResumptionTokenExpired = errors.New("Resumption token has expired")
+
+type HTTPError struct {
+	StatusCode int
+	Body       []byte
+	retryAfter time.Duration
+}
+
+type RecordError struct {
+	Identifier string
+	Err        error
+}
+
+func (c ErrorCode) Error() string {
+	return string(c)
+}
+
 func (e Error) Error() string {
 	return fmt.Sprintf("%v: %v", e.Code, e.Message)
 }
@@ -11,3 +45,63 @@ func (e Error) Error() string {
 func (e Error) Empty() bool {
 	return e.Code == "" && e.Message == ""
 }
+
+// Is allows errors.Is(err, ErrNoRecordsMatch) and the like to match on code.
+func (e Error) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+
+	return ok && e.Code == code
+}
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+
+	for i, err := range e {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// err returns nil when no errors are present, and a lone Error unwrapped so
+// existing type assertions on Error keep working.
+func (e Errors) err() error {
+	var present Errors
+
+	for _, err := range e {
+		if !err.Empty() {
+			present = append(present, err)
+		}
+	}
+
+	switch len(present) {
+	case 0:
+		return nil
+	case 1:
+		return present[0]
+	}
+
+	return present
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("Unsuccessful request: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("Unable to decode record %s: %v", e.Identifier, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
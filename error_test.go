@@ -2,6 +2,7 @@ package oaipmh
 
 import (
 	"encoding/xml"
+	"errors"
 	. "gopkg.in/check.v1"
 )
 
@@ -48,3 +49,105 @@ func (s *errorSuite) TestErrorIndicatesNonEmptyWhenMessageIsAssigned(c *C) {
 
 	c.Assert(err.Empty(), Equals, false)
 }
+
+func (s *errorSuite) TestErrorMatchesItsCodeWithErrorsIs(c *C) {
+	var err error = Error{
+		XMLName: xml.Name{},
+		Message: "No items match",
+		Code:    ErrNoRecordsMatch,
+	}
+
+	c.Assert(errors.Is(err, ErrNoRecordsMatch), Equals, true)
+	c.Assert(errors.Is(err, ErrBadResumptionToken), Equals, false)
+}
+
+func (s *errorSuite) TestErrorCodeReturnsCode(c *C) {
+	c.Assert(ErrCannotDisseminateFormat.Error(), Equals, "cannotDisseminateFormat")
+}
+
+func (s *errorSuite) TestHTTPErrorReturnsFormattedString(c *C) {
+	err := &HTTPError{StatusCode: 503, Body: []byte("busy")}
+
+	c.Assert(err.Error(), Equals, "Unsuccessful request: 503 Service Unavailable")
+}
+
+func (s *errorSuite) TestResponseErrorsCanBeMatchedByCode(c *C) {
+	raw := `
+<?xml version='1.0' encoding='UTF-8'?>
+<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/">
+  <responseDate>2016-03-27T17:54:02Z</responseDate>
+  <request>http://eprints.ecs.soton.ac.uk/cgi/oai2</request>
+  <error code="idDoesNotExist">'oai:eprints.ecs.soton.ac.uk:99999' is not a valid item in this repository</error>
+</OAI-PMH>`
+
+	server, client := mockClient(200, raw)
+	defer server.Close()
+	_, _, err := client.GetRecord(&GetRecordOptions{"oai:eprints.ecs.soton.ac.uk:99999", "oai_dc"}, new(DublinCoreRecord))
+
+	var oaiErr Error
+
+	c.Assert(errors.Is(err, ErrIDDoesNotExist), Equals, true)
+	c.Assert(errors.As(err, &oaiErr), Equals, true)
+	c.Assert(oaiErr.Code, Equals, ErrIDDoesNotExist)
+}
+
+func (s *errorSuite) TestUnsuccessfulResponsesReturnHTTPError(c *C) {
+	server, client := mockClient(404, "Not Found")
+	defer server.Close()
+	_, _, err := client.Identify()
+
+	var httpErr *HTTPError
+
+	c.Assert(errors.As(err, &httpErr), Equals, true)
+	c.Assert(httpErr.StatusCode, Equals, 404)
+	c.Assert(string(httpErr.Body), Equals, "Not Found\n")
+}
+
+func (s *errorSuite) TestErrorsReturnsEveryMessage(c *C) {
+	err := Errors{
+		Error{Code: ErrBadArgument, Message: "foo"},
+		Error{Code: ErrBadVerb, Message: "bar"},
+	}
+
+	c.Assert(err.Error(), Equals, "badArgument: foo; badVerb: bar")
+}
+
+func (s *errorSuite) TestErrorsMatchesEveryCodeWithErrorsIs(c *C) {
+	var err error = Errors{
+		Error{Code: ErrBadArgument, Message: "foo"},
+		Error{Code: ErrBadVerb, Message: "bar"},
+	}
+
+	var oaiErr Error
+
+	c.Assert(errors.Is(err, ErrBadArgument), Equals, true)
+	c.Assert(errors.Is(err, ErrBadVerb), Equals, true)
+	c.Assert(errors.Is(err, ErrNoRecordsMatch), Equals, false)
+	c.Assert(errors.As(err, &oaiErr), Equals, true)
+	c.Assert(oaiErr.Code, Equals, ErrBadArgument)
+}
+
+func (s *errorSuite) TestErrorsCollapsesToSingleOrNoError(c *C) {
+	c.Assert(Errors{}.err(), IsNil)
+	c.Assert(Errors{Error{}}.err(), IsNil)
+	c.Assert(Errors{Error{Code: ErrBadVerb}}.err(), Equals, Error{Code: ErrBadVerb})
+}
+
+func (s *errorSuite) TestResponsesWithMultipleErrorsReturnAllOfThem(c *C) {
+	raw := `
+<?xml version='1.0' encoding='UTF-8'?>
+<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/">
+  <responseDate>2016-03-27T17:54:02Z</responseDate>
+  <request>http://eprints.ecs.soton.ac.uk/cgi/oai2</request>
+  <error code="badVerb">Illegal verb</error>
+  <error code="badArgument">Illegal argument 'x'</error>
+</OAI-PMH>`
+
+	server, client := mockClient(200, raw)
+	defer server.Close()
+	_, _, err := client.Identify()
+
+	c.Assert(err, ErrorMatches, "badVerb: Illegal verb; badArgument: Illegal argument 'x'")
+	c.Assert(errors.Is(err, ErrBadVerb), Equals, true)
+	c.Assert(errors.Is(err, ErrBadArgument), Equals, true)
+}
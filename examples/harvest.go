@@ -1,36 +1,32 @@
 package main
 
 import (
-	"github.com/nick-jones/oaipmh"
+	"encoding/xml"
 	"fmt"
+	"github.com/nick-jones/oaipmh"
 	"os"
-	"time"
 )
 
 func main() {
 	baseURL := "http://eprints.ecs.soton.ac.uk/cgi/oai2"
 	client, _ := oaipmh.NewClient(baseURL)
-	options := &oaipmh.ListOptions{"oai_dc", time.Time{}, time.Time{}, "", ""}
+	records := client.IterateRecords(oaipmh.ListOptions{MetadataPrefix: "oai_dc"})
+	defer records.Close()
 
-	for {
-		records := new(oaipmh.DublinCoreRecords)
-		response, _, err := client.ListRecords(options, records)
-		resumptionToken := response.ResumptionToken.Value
-		options = &oaipmh.ListOptions{"", time.Time{}, time.Time{}, "", resumptionToken}
+	for records.Next() {
+		record := new(oaipmh.DublinCoreRecord)
 
-		if err != nil {
-			fmt.Println(err.Error())
-			os.Exit(1)
+		if err := xml.Unmarshal(records.Record().Metadata.Raw, record); err != nil {
+			continue
 		}
 
-		for _, record := range records.Records {
-			if len(record.Titles) > 0 {
-				fmt.Fprintf(os.Stdout, "title: %s\n", record.Titles[0])
-			}
+		if len(record.Titles) > 0 {
+			fmt.Fprintf(os.Stdout, "title: %s\n", record.Titles[0])
 		}
+	}
 
-		if resumptionToken == "" {
-			break
-		}
+	if err := records.Err(); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
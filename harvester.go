@@ -0,0 +1,248 @@
+package oaipmh
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Endpoint struct {
+	BaseURL string
+	Options ListOptions
+	// ClientOptions are applied after the Harvester's own, so they win.
+	ClientOptions []Option
+}
+
+// Harvester runs ListRecords across many repositories. Handle is called from
+// several goroutines at once, though never concurrently for one endpoint.
+type Harvester struct {
+	Handle func(endpoint Endpoint, record Record) error
+	// Concurrency bounds how many endpoints are harvested at once, and
+	// PerHost how many of those may share a host. Values below one mean one.
+	Concurrency   int
+	PerHost       int
+	RateLimiter   *RateLimiter
+	ClientOptions []Option
+	// State makes harvests incremental: each endpoint starts from where its
+	// last successful harvest finished, stepped back by Overlap.
+	State   StateStore
+	Overlap time.Duration
+	// Checkpoints lets an interrupted harvest continue from its last page.
+	Checkpoints CheckpointStore
+}
+
+type EndpointReport struct {
+	Endpoint  Endpoint
+	Records   int
+	Deletions int
+	Pages     int
+	Err       error
+	Duration  time.Duration
+}
+
+type Report struct {
+	Endpoints []EndpointReport
+	Duration  time.Duration
+}
+
+type hostSlots struct {
+	mu    sync.Mutex
+	size  int
+	slots map[string]chan struct{}
+}
+
+// Harvest runs every endpoint to completion. A failing endpoint is recorded
+// in its report and does not affect the others.
+func (h *Harvester) Harvest(ctx context.Context, endpoints []Endpoint) Report {
+	started := time.Now()
+	report := Report{Endpoints: make([]EndpointReport, len(endpoints))}
+	slots := make(chan struct{}, atLeastOne(h.Concurrency))
+	hosts := &hostSlots{size: atLeastOne(h.PerHost), slots: map[string]chan struct{}{}}
+	wg := sync.WaitGroup{}
+
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+
+		go func(i int, endpoint Endpoint) {
+			defer wg.Done()
+
+			report.Endpoints[i] = EndpointReport{Endpoint: endpoint}
+			host := hosts.get(endpoint.BaseURL)
+
+			if err := acquire(ctx, host); err != nil {
+				report.Endpoints[i].Err = err
+				return
+			}
+
+			defer func() { <-host }()
+
+			if err := acquire(ctx, slots); err != nil {
+				report.Endpoints[i].Err = err
+				return
+			}
+
+			defer func() { <-slots }()
+
+			h.harvestEndpoint(ctx, &report.Endpoints[i])
+		}(i, endpoint)
+	}
+
+	wg.Wait()
+	report.Duration = time.Since(started)
+
+	return report
+}
+
+func (h *Harvester) harvestEndpoint(ctx context.Context, report *EndpointReport) {
+	started := time.Now()
+
+	defer func() {
+		if r := recover(); r != nil {
+			report.Err = fmt.Errorf("Harvest of %s panicked: %v", redactURL(report.Endpoint.BaseURL), r)
+		}
+
+		report.Duration = time.Since(started)
+	}()
+
+	client, err := NewClient(report.Endpoint.BaseURL, h.clientOptions(report.Endpoint)...)
+
+	if err != nil {
+		report.Err = err
+		return
+	}
+
+	key := StateKey{storeURL(report.Endpoint.BaseURL), report.Endpoint.Options.Set, report.Endpoint.Options.MetadataPrefix}
+	state, options, err := h.resume(ctx, client, key, report.Endpoint.Options)
+
+	if err != nil {
+		report.Err = err
+		return
+	}
+
+	iterateOptions := []IterateOption{}
+
+	if h.Checkpoints != nil {
+		iterateOptions = append(iterateOptions, WithCheckpoints(h.Checkpoints))
+	}
+
+	records := client.IterateRecordsContext(ctx, options, iterateOptions...)
+	defer records.Close()
+
+	for records.Next() {
+		record := records.Record()
+		state.observe(record.Header.Datestamp)
+
+		if record.Header.IsDeleted() {
+			report.Deletions++
+		} else {
+			report.Records++
+		}
+
+		if h.Handle != nil {
+			if err := h.Handle(report.Endpoint, record); err != nil {
+				report.Err = err
+				break
+			}
+		}
+	}
+
+	report.Pages = records.Progress().Pages
+
+	if report.Err == nil {
+		report.Err = records.Err()
+	}
+
+	if report.Err == nil && h.State != nil {
+		if !records.ResponseDate().IsZero() {
+			state.ResponseDate = records.ResponseDate()
+		}
+
+		report.Err = h.State.Save(ctx, key, state)
+	}
+}
+
+// resume loads the saved state for an endpoint and, unless the caller fixed a
+// starting point themselves, moves From up to it.
+func (h *Harvester) resume(ctx context.Context, client *Client, key StateKey, options ListOptions) (HarvestState, ListOptions, error) {
+	if h.State == nil {
+		return HarvestState{}, options, nil
+	}
+
+	state, found, err := h.State.Load(ctx, key)
+
+	if err != nil || !found || !options.From.IsZero() || options.ResumptionToken != "" {
+		return state, options, err
+	}
+
+	granularity, err := client.repositoryGranularity(ctx)
+
+	if err != nil {
+		return state, options, err
+	}
+
+	if from, ok := state.resumeFrom(granularity, h.Overlap); ok {
+		options.From = from
+	}
+
+	return state, options, nil
+}
+
+func (h *Harvester) clientOptions(endpoint Endpoint) []Option {
+	options := append([]Option{}, h.ClientOptions...)
+
+	if h.RateLimiter != nil {
+		options = append(options, WithRateLimiter(h.RateLimiter))
+	}
+
+	return append(options, endpoint.ClientOptions...)
+}
+
+// Failed returns the reports of endpoints that ended in error.
+func (r Report) Failed() []EndpointReport {
+	failed := []EndpointReport{}
+
+	for _, endpoint := range r.Endpoints {
+		if endpoint.Err != nil {
+			failed = append(failed, endpoint)
+		}
+	}
+
+	return failed
+}
+
+func (s *hostSlots) get(baseURL string) chan struct{} {
+	host := baseURL
+
+	if u, err := url.Parse(baseURL); err == nil {
+		host = strings.ToLower(u.Host)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.slots[host]; !ok {
+		s.slots[host] = make(chan struct{}, s.size)
+	}
+
+	return s.slots[host]
+}
+
+func acquire(ctx context.Context, slots chan struct{}) error {
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+
+	return n
+}
@@ -0,0 +1,132 @@
+package oaipmh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	. "gopkg.in/check.v1"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+type harvesterSuite struct{}
+
+var _ = Suite(&harvesterSuite{})
+
+func (s *harvesterSuite) TestHarvestReportsEachEndpoint(c *C) {
+	good, _, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListRecords>
+		  <record><header><identifier>a</identifier></header></record>
+		  <resumptionToken>page2</resumptionToken>
+		</ListRecords>`,
+		"page2": `<ListRecords>
+		  <record><header status="deleted"><identifier>b</identifier></header></record>
+		  <record><header><identifier>c</identifier></header></record>
+		  <resumptionToken/>
+		</ListRecords>`,
+	})
+	defer good.Close()
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	mu := sync.Mutex{}
+	handled := []string{}
+	harvester := &Harvester{
+		Concurrency:   2,
+		ClientOptions: []Option{WithRetryPolicy(NoRetryPolicy)},
+		Handle: func(endpoint Endpoint, record Record) error {
+			mu.Lock()
+			defer mu.Unlock()
+			handled = append(handled, record.Header.Identifier)
+			return nil
+		},
+	}
+
+	report := harvester.Harvest(context.Background(), []Endpoint{
+		{BaseURL: good.URL, Options: ListOptions{MetadataPrefix: "oai_dc"}},
+		{BaseURL: broken.URL, Options: ListOptions{MetadataPrefix: "oai_dc"}},
+		{BaseURL: "not a url"},
+	})
+
+	c.Assert(report.Endpoints, HasLen, 3)
+	c.Assert(report.Endpoints[0].Err, IsNil)
+	c.Assert(report.Endpoints[0].Records, Equals, 2)
+	c.Assert(report.Endpoints[0].Deletions, Equals, 1)
+	c.Assert(report.Endpoints[0].Pages, Equals, 2)
+	c.Assert(report.Endpoints[1].Err, FitsTypeOf, &HTTPError{})
+	c.Assert(report.Endpoints[2].Err, ErrorMatches, "Invalid base URL.*")
+	c.Assert(report.Failed(), HasLen, 2)
+	c.Assert(handled, DeepEquals, []string{"a", "b", "c"})
+}
+
+func (s *harvesterSuite) TestHandlerErrorsAndPanicsAreIsolated(c *C) {
+	server, _, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListRecords><record><header><identifier>a</identifier></header></record></ListRecords>`,
+	})
+	defer server.Close()
+
+	harvester := &Harvester{Handle: func(endpoint Endpoint, record Record) error {
+		if endpoint.Options.Set == "panic" {
+			panic("boom")
+		}
+
+		return errors.New("Rejected")
+	}}
+
+	report := harvester.Harvest(context.Background(), []Endpoint{
+		{BaseURL: strings.Replace(server.URL, "http://", "http://user:secret@", 1), Options: ListOptions{Set: "panic"}},
+		{BaseURL: server.URL},
+	})
+
+	c.Assert(report.Endpoints[0].Err, ErrorMatches, "Harvest of http://user:xxxxx@.* panicked: boom")
+	c.Assert(report.Endpoints[1].Err, ErrorMatches, "Rejected")
+}
+
+func (s *harvesterSuite) TestConcurrencyIsBoundedPerHost(c *C) {
+	mu := sync.Mutex{}
+	inFlight, peak := 0, 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+
+		if inFlight > peak {
+			peak = inFlight
+		}
+
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprintf(w, pageTemplate, `<ListRecords><record><header><identifier>a</identifier></header></record></ListRecords>`)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	endpoints := []Endpoint{}
+
+	for i := 0; i < 6; i++ {
+		endpoints = append(endpoints, Endpoint{BaseURL: fmt.Sprintf("%s/%d", server.URL, i)})
+	}
+
+	report := (&Harvester{Concurrency: 4, PerHost: 2}).Harvest(context.Background(), endpoints)
+
+	c.Assert(report.Failed(), HasLen, 0)
+	c.Assert(peak <= 2, Equals, true)
+}
+
+func (s *harvesterSuite) TestCancelledHarvestReportsContextError(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report := (&Harvester{}).Harvest(ctx, []Endpoint{{BaseURL: "http://example.org/oai"}})
+
+	c.Assert(errors.Is(report.Endpoints[0].Err, context.Canceled), Equals, true)
+}
@@ -0,0 +1,383 @@
+package oaipmh
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type RecordIterator struct {
+	pager       *pager
+	skipDeleted bool
+	record      Record
+}
+
+type IdentifierIterator struct {
+	pager       *pager
+	skipDeleted bool
+	header      RecordHeader
+}
+
+type SetIterator struct {
+	pager *pager
+	set   Set
+}
+
+// Progress describes how far through a list an iterator has paged. Fetched
+// follows the cursor reported by the repository where one is given.
+type Progress struct {
+	Pages            int
+	Fetched          int
+	CompleteListSize *int
+}
+
+type pager struct {
+	open         func(token string) (*listStream, error)
+	stream       *listStream
+	count        int
+	token        string
+	last         ResumptionToken
+	progress     Progress
+	responseDate Datestamp
+	initial      string
+	checkpoints  *checkpointing
+	done         bool
+	err          error
+}
+
+func (c *Client) IterateRecords(options ListOptions, iterateOptions ...IterateOption) *RecordIterator {
+	return c.IterateRecordsContext(context.Background(), options, iterateOptions...)
+}
+
+func (c *Client) IterateRecordsContext(ctx context.Context, options ListOptions, iterateOptions ...IterateOption) *RecordIterator {
+	key := c.checkpointKey(ctx, "ListRecords", options)
+
+	return &RecordIterator{pager: newPager(ctx, key, options.ResumptionToken, func(token string) (*listStream, error) {
+		params, err := c.listParameters(ctx, "ListRecords", pageOptions(options, token))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return c.openStream(ctx, params, "record")
+	}, iterateOptions), skipDeleted: c.deleted == SkipDeletedRecords}
+}
+
+func (c *Client) IterateIdentifiers(options ListOptions, iterateOptions ...IterateOption) *IdentifierIterator {
+	return c.IterateIdentifiersContext(context.Background(), options, iterateOptions...)
+}
+
+func (c *Client) IterateIdentifiersContext(ctx context.Context, options ListOptions, iterateOptions ...IterateOption) *IdentifierIterator {
+	key := c.checkpointKey(ctx, "ListIdentifiers", options)
+
+	return &IdentifierIterator{pager: newPager(ctx, key, options.ResumptionToken, func(token string) (*listStream, error) {
+		params, err := c.listParameters(ctx, "ListIdentifiers", pageOptions(options, token))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return c.openStream(ctx, params, "header")
+	}, iterateOptions), skipDeleted: c.deleted == SkipDeletedRecords}
+}
+
+func (c *Client) IterateSets(options ListSetsOptions, iterateOptions ...IterateOption) *SetIterator {
+	return c.IterateSetsContext(context.Background(), options, iterateOptions...)
+}
+
+func (c *Client) IterateSetsContext(ctx context.Context, options ListSetsOptions, iterateOptions ...IterateOption) *SetIterator {
+	key := func() (CheckpointKey, error) {
+		return CheckpointKey{Verb: "ListSets", BaseURL: storeURL(c.baseURL)}, nil
+	}
+
+	return &SetIterator{pager: newPager(ctx, key, options.ResumptionToken, func(token string) (*listStream, error) {
+		params := prepareParameters("ListSets", map[string]string{"resumptionToken": token})
+
+		return c.openStream(ctx, params, "set")
+	}, iterateOptions)}
+}
+
+// checkpointKey resolves the key of a list once its first page is due, as the
+// dates may need the repository granularity from an Identify.
+func (c *Client) checkpointKey(ctx context.Context, verb string, options ListOptions) func() (CheckpointKey, error) {
+	return func() (CheckpointKey, error) {
+		params, err := c.listParameters(ctx, verb, &options)
+
+		if err != nil {
+			return CheckpointKey{}, err
+		}
+
+		return CheckpointKey{verb, storeURL(c.baseURL), options.Set, options.MetadataPrefix, params.Get("from"), params.Get("until")}, nil
+	}
+}
+
+func newPager(ctx context.Context, key func() (CheckpointKey, error), token string, open func(token string) (*listStream, error), options []IterateOption) *pager {
+	p := &pager{open: open, token: token, initial: token}
+
+	for _, option := range options {
+		option(p)
+	}
+
+	if p.checkpoints != nil {
+		p.checkpoints.ctx = ctx
+		p.checkpoints.resolve = key
+	}
+
+	return p
+}
+
+func (it *RecordIterator) Next() bool {
+	for {
+		it.record = Record{}
+
+		if !it.pager.next(&it.record) {
+			return false
+		}
+
+		if !it.skipDeleted || !it.record.Header.IsDeleted() {
+			return true
+		}
+	}
+}
+
+func (it *RecordIterator) Record() Record {
+	return it.record
+}
+
+func (it *RecordIterator) ResumptionToken() ResumptionToken {
+	return it.pager.last
+}
+
+func (it *RecordIterator) Progress() Progress {
+	return it.pager.progress
+}
+
+// ResponseDate is the responseDate of the first page, which is the point a
+// later incremental harvest can safely resume from.
+func (it *RecordIterator) ResponseDate() Datestamp {
+	return it.pager.responseDate
+}
+
+func (it *RecordIterator) Err() error {
+	return it.pager.err
+}
+
+// Close releases the page being read. It only needs calling when iteration is
+// abandoned before Next returns false.
+func (it *RecordIterator) Close() error {
+	return it.pager.stop()
+}
+
+func (it *IdentifierIterator) Next() bool {
+	for {
+		it.header = RecordHeader{}
+
+		if !it.pager.next(&it.header) {
+			return false
+		}
+
+		if !it.skipDeleted || !it.header.IsDeleted() {
+			return true
+		}
+	}
+}
+
+func (it *IdentifierIterator) Header() RecordHeader {
+	return it.header
+}
+
+func (it *IdentifierIterator) ResumptionToken() ResumptionToken {
+	return it.pager.last
+}
+
+func (it *IdentifierIterator) Progress() Progress {
+	return it.pager.progress
+}
+
+func (it *IdentifierIterator) Err() error {
+	return it.pager.err
+}
+
+// Close releases the page being read. It only needs calling when iteration is
+// abandoned before Next returns false.
+func (it *IdentifierIterator) Close() error {
+	return it.pager.stop()
+}
+
+func (it *SetIterator) Next() bool {
+	it.set = Set{}
+
+	return it.pager.next(&it.set)
+}
+
+func (it *SetIterator) Set() Set {
+	return it.set
+}
+
+func (it *SetIterator) ResumptionToken() ResumptionToken {
+	return it.pager.last
+}
+
+func (it *SetIterator) Progress() Progress {
+	return it.pager.progress
+}
+
+func (it *SetIterator) Err() error {
+	return it.pager.err
+}
+
+// Close releases the page being read. It only needs calling when iteration is
+// abandoned before Next returns false.
+func (it *SetIterator) Close() error {
+	return it.pager.stop()
+}
+
+func (p Progress) Percent() (float64, bool) {
+	if p.CompleteListSize == nil || *p.CompleteListSize <= 0 {
+		return 0, false
+	}
+
+	return 100 * float64(p.Fetched) / float64(*p.CompleteListSize), true
+}
+
+// Expired reports false where the repository gave no usable expirationDate.
+func (t ResumptionToken) Expired(now time.Time) bool {
+	if t.ExpirationDate.IsZero() {
+		return false
+	}
+
+	expiration, err := t.ExpirationDate.Time()
+
+	return err == nil && now.After(expiration)
+}
+
+func (p *pager) next(into interface{}) bool {
+	for {
+		if p.stream == nil && !p.openPage() {
+			return false
+		}
+
+		ok, err := p.stream.next(into)
+
+		if ok {
+			if p.responseDate == "" {
+				p.responseDate = p.stream.responseDate
+			}
+
+			p.count++
+			return true
+		}
+
+		token := p.stream.token
+		p.close()
+
+		if err != nil && p.restart(err) {
+			continue
+		}
+
+		if err != nil {
+			p.fail(err)
+			return false
+		}
+
+		p.record(token, p.count)
+		p.token = token.Value
+		p.done = p.token == ""
+
+		if err := p.checkpoint(); err != nil {
+			p.err = err
+			return false
+		}
+	}
+}
+
+func (p *pager) openPage() bool {
+	if p.done || p.err != nil {
+		return false
+	}
+
+	if err := p.restore(); err != nil {
+		p.err = err
+		return false
+	}
+
+	if p.progress.Pages > 0 && p.last.Expired(time.Now()) {
+		if p.restart(ErrResumptionTokenExpired) {
+			return p.openPage()
+		}
+
+		p.err = ErrResumptionTokenExpired
+		return false
+	}
+
+	stream, err := p.open(p.token)
+
+	if err != nil && p.restart(err) {
+		return p.openPage()
+	}
+
+	if err != nil {
+		p.fail(err)
+		return false
+	}
+
+	p.stream = stream
+	p.count = 0
+
+	return true
+}
+
+func (p *pager) fail(err error) {
+	if p.progress.Pages == 0 && isEmptyListError(err) {
+		p.done = true
+	} else {
+		p.err = err
+	}
+}
+
+func (p *pager) stop() error {
+	p.done = true
+
+	return p.close()
+}
+
+func (p *pager) close() error {
+	if p.stream == nil {
+		return nil
+	}
+
+	if p.responseDate == "" {
+		p.responseDate = p.stream.responseDate
+	}
+
+	err := p.stream.close()
+	p.stream = nil
+
+	return err
+}
+
+func (p *pager) record(token ResumptionToken, count int) {
+	p.last = token
+	p.progress.Pages++
+	p.progress.Fetched += count
+
+	if token.Cursor != nil {
+		p.progress.Fetched = *token.Cursor + count
+	}
+
+	if token.CompleteListSize != nil {
+		p.progress.CompleteListSize = token.CompleteListSize
+	}
+}
+
+func pageOptions(options ListOptions, token string) *ListOptions {
+	if token == "" {
+		return &options
+	}
+
+	return &ListOptions{ResumptionToken: token}
+}
+
+func isEmptyListError(err error) bool {
+	return errors.Is(err, ErrNoRecordsMatch) || errors.Is(err, ErrNoSetHierarchy)
+}
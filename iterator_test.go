@@ -0,0 +1,344 @@
+package oaipmh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	. "gopkg.in/check.v1"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+type iteratorSuite struct{}
+
+var _ = Suite(&iteratorSuite{})
+
+const pageTemplate = `
+<?xml version='1.0' encoding='UTF-8'?>
+<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/">
+  <responseDate>2016-03-27T18:20:04Z</responseDate>
+  <request verb="ListRecords">http://example.org/oai</request>
+  %s
+</OAI-PMH>`
+
+// mockQueryClient serves the status and page chosen by respond, wrapped in
+// pageTemplate, recording the value of key from each request. Unless key is
+// the verb, the Identify sent ahead of the first request is answered with an
+// empty one and not recorded.
+func mockQueryClient(key string, respond func(query url.Values) (int, string), options ...Option) (*httptest.Server, *Client, *[]string) {
+	mu := sync.Mutex{}
+	requested := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key != "verb" && r.URL.Query().Get("verb") == "Identify" {
+			fmt.Fprintf(w, pageTemplate, "<Identify></Identify>")
+			return
+		}
+
+		mu.Lock()
+		requested = append(requested, r.URL.Query().Get(key))
+		mu.Unlock()
+
+		status, page := respond(r.URL.Query())
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, pageTemplate, page)
+	}))
+
+	client, _ := NewClient(server.URL, options...)
+
+	return server, client, &requested
+}
+
+// mockPagedClient serves a page per value of the key query parameter, such as
+// resumptionToken, with the empty value used when the parameter is absent.
+func mockPagedClient(key string, pages map[string]string, options ...Option) (*httptest.Server, *Client, *[]string) {
+	return mockQueryClient(key, func(query url.Values) (int, string) {
+		return http.StatusOK, pages[query.Get(key)]
+	}, options...)
+}
+
+func (s *iteratorSuite) TestIterateRecordsFollowsResumptionTokens(c *C) {
+	server, client, requested := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListRecords>
+		  <record><header><identifier>a</identifier></header></record>
+		  <record><header><identifier>b</identifier></header></record>
+		  <resumptionToken>page2</resumptionToken>
+		</ListRecords>`,
+		"page2": `<ListRecords>
+		  <record><header><identifier>c</identifier></header></record>
+		  <resumptionToken></resumptionToken>
+		</ListRecords>`,
+	})
+	defer server.Close()
+
+	iterator := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"})
+	identifiers := []string{}
+
+	for iterator.Next() {
+		identifiers = append(identifiers, iterator.Record().Header.Identifier)
+	}
+
+	c.Assert(iterator.Err(), IsNil)
+	c.Assert(identifiers, DeepEquals, []string{"a", "b", "c"})
+	c.Assert(*requested, DeepEquals, []string{"", "page2"})
+}
+
+func (s *iteratorSuite) TestIterateRecordsSkipsEmptyPages(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListRecords>
+		  <resumptionToken>page2</resumptionToken>
+		</ListRecords>`,
+		"page2": `<ListRecords>
+		  <record><header><identifier>a</identifier></header></record>
+		</ListRecords>`,
+	})
+	defer server.Close()
+
+	iterator := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(iterator.Next(), Equals, true)
+	c.Assert(iterator.Record().Header.Identifier, Equals, "a")
+	c.Assert(iterator.Next(), Equals, false)
+	c.Assert(iterator.Err(), IsNil)
+}
+
+func (s *iteratorSuite) TestIterateRecordsTreatsNoRecordsMatchAsEmpty(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<error code="noRecordsMatch">No items match</error>`,
+	})
+	defer server.Close()
+
+	iterator := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(iterator.Next(), Equals, false)
+	c.Assert(iterator.Err(), IsNil)
+}
+
+func (s *iteratorSuite) TestIterateRecordsReportsErrors(c *C) {
+	server, client, requested := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListRecords>
+		  <record><header><identifier>a</identifier></header></record>
+		  <resumptionToken>page2</resumptionToken>
+		</ListRecords>`,
+		"page2": `<error code="badResumptionToken">Expired</error>`,
+	})
+	defer server.Close()
+
+	iterator := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(iterator.Next(), Equals, true)
+	c.Assert(iterator.Next(), Equals, false)
+	c.Assert(iterator.Err(), ErrorMatches, "badResumptionToken: Expired")
+	c.Assert(iterator.Next(), Equals, false)
+	c.Assert(*requested, DeepEquals, []string{"", "page2"})
+}
+
+func (s *iteratorSuite) TestIterateRecordsSendsOnlyTokenAfterFirstPage(c *C) {
+	var queries []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+
+		if r.URL.Query().Get("resumptionToken") == "" {
+			fmt.Fprintf(w, pageTemplate, `<ListRecords><resumptionToken>t</resumptionToken></ListRecords>`)
+		} else {
+			fmt.Fprintf(w, pageTemplate, `<ListRecords></ListRecords>`)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(server.URL, WithCompression())
+	iterator := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc", Set: "s"})
+
+	for iterator.Next() {
+	}
+
+	c.Assert(iterator.Err(), IsNil)
+	c.Assert(queries, DeepEquals, []string{
+		"metadataPrefix=oai_dc&set=s&verb=ListRecords",
+		"resumptionToken=t&verb=ListRecords",
+	})
+}
+
+func (s *iteratorSuite) TestIterateIdentifiersFollowsResumptionTokens(c *C) {
+	server, client, requested := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListIdentifiers>
+		  <header><identifier>a</identifier></header>
+		  <resumptionToken>page2</resumptionToken>
+		</ListIdentifiers>`,
+		"page2": `<ListIdentifiers>
+		  <header status="deleted"><identifier>b</identifier></header>
+		</ListIdentifiers>`,
+	})
+	defer server.Close()
+
+	iterator := client.IterateIdentifiers(ListOptions{MetadataPrefix: "oai_dc"})
+	identifiers := []string{}
+
+	for iterator.Next() {
+		identifiers = append(identifiers, iterator.Header().Identifier)
+	}
+
+	c.Assert(iterator.Err(), IsNil)
+	c.Assert(identifiers, DeepEquals, []string{"a", "b"})
+	c.Assert(*requested, DeepEquals, []string{"", "page2"})
+}
+
+func (s *iteratorSuite) TestIterateIdentifiersTreatsNoRecordsMatchAsEmpty(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<error code="noRecordsMatch">No items match</error>`,
+	})
+	defer server.Close()
+
+	iterator := client.IterateIdentifiers(ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(iterator.Next(), Equals, false)
+	c.Assert(iterator.Err(), IsNil)
+}
+
+func (s *iteratorSuite) TestIterateSetsFollowsResumptionTokens(c *C) {
+	server, client, requested := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListSets>
+		  <set><setSpec>math</setSpec><setName>Mathematics</setName></set>
+		  <resumptionToken>page2</resumptionToken>
+		</ListSets>`,
+		"page2": `<ListSets>
+		  <set><setSpec>math:algebra</setSpec><setName>Algebra</setName></set>
+		</ListSets>`,
+	})
+	defer server.Close()
+
+	iterator := client.IterateSets(ListSetsOptions{})
+	specs := []string{}
+
+	for iterator.Next() {
+		specs = append(specs, iterator.Set().SetSpec)
+	}
+
+	c.Assert(iterator.Err(), IsNil)
+	c.Assert(specs, DeepEquals, []string{"math", "math:algebra"})
+	c.Assert(*requested, DeepEquals, []string{"", "page2"})
+}
+
+func (s *iteratorSuite) TestIterateSetsTreatsNoSetHierarchyAsEmpty(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<error code="noSetHierarchy">Sets are not supported</error>`,
+	})
+	defer server.Close()
+
+	iterator := client.IterateSets(ListSetsOptions{})
+
+	c.Assert(iterator.Next(), Equals, false)
+	c.Assert(iterator.Err(), IsNil)
+}
+
+func (s *iteratorSuite) TestIterateRecordsStopsWhenContextIsCancelled(c *C) {
+	server, client, requested := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListRecords>
+		  <record><header><identifier>a</identifier></header></record>
+		  <resumptionToken>page2</resumptionToken>
+		</ListRecords>`,
+	})
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	iterator := client.IterateRecordsContext(ctx, ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(iterator.Next(), Equals, true)
+	cancel()
+	c.Assert(iterator.Next(), Equals, false)
+	c.Assert(errors.Is(iterator.Err(), context.Canceled), Equals, true)
+	c.Assert(*requested, DeepEquals, []string{""})
+}
+
+func (s *iteratorSuite) TestResumptionTokenAttributesAreParsed(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListIdentifiers>
+		  <header><identifier>a</identifier></header>
+		  <resumptionToken expirationDate="2099-01-01T00:00:00Z" completeListSize="4" cursor="0">page2</resumptionToken>
+		</ListIdentifiers>`,
+	})
+	defer server.Close()
+
+	response, _, err := client.ListIdentifiers(&ListOptions{MetadataPrefix: "oai_dc"})
+	token := response.ResumptionToken
+	expiration, _ := token.ExpirationDate.Time()
+
+	c.Assert(err, IsNil)
+	c.Assert(*token.CompleteListSize, Equals, 4)
+	c.Assert(*token.Cursor, Equals, 0)
+	c.Assert(expiration, Equals, time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func (s *iteratorSuite) TestIteratorReportsProgress(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListRecords>
+		  <record><header><identifier>a</identifier></header></record>
+		  <record><header><identifier>b</identifier></header></record>
+		  <resumptionToken completeListSize="3" cursor="0">page2</resumptionToken>
+		</ListRecords>`,
+		"page2": `<ListRecords>
+		  <record><header><identifier>c</identifier></header></record>
+		  <resumptionToken completeListSize="3" cursor="2"></resumptionToken>
+		</ListRecords>`,
+	})
+	defer server.Close()
+
+	iterator := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(iterator.Next(), Equals, true)
+	c.Assert(iterator.Progress().Pages, Equals, 0)
+	c.Assert(iterator.Next(), Equals, true)
+	c.Assert(iterator.Next(), Equals, true)
+	c.Assert(iterator.Record().Header.Identifier, Equals, "c")
+	percent, ok := iterator.Progress().Percent()
+	c.Assert(iterator.Progress().Pages, Equals, 1)
+	c.Assert(iterator.Progress().Fetched, Equals, 2)
+	c.Assert(ok, Equals, true)
+	c.Assert(int(percent), Equals, 66)
+	c.Assert(iterator.ResumptionToken().Value, Equals, "page2")
+
+	for iterator.Next() {
+	}
+
+	percent, _ = iterator.Progress().Percent()
+	c.Assert(iterator.Err(), IsNil)
+	c.Assert(iterator.Progress().Pages, Equals, 2)
+	c.Assert(percent, Equals, 100.0)
+}
+
+func (s *iteratorSuite) TestProgressWithoutCompleteListSizeHasNoPercentage(c *C) {
+	_, ok := Progress{Pages: 1, Fetched: 10}.Percent()
+
+	c.Assert(ok, Equals, false)
+}
+
+func (s *iteratorSuite) TestIteratorFailsFastOnExpiredToken(c *C) {
+	server, client, requested := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListRecords>
+		  <record><header><identifier>a</identifier></header></record>
+		  <resumptionToken expirationDate="2000-01-01T00:00:00Z">page2</resumptionToken>
+		</ListRecords>`,
+	})
+	defer server.Close()
+
+	iterator := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(iterator.Next(), Equals, true)
+	c.Assert(iterator.Next(), Equals, false)
+	c.Assert(iterator.Err(), Equals, ErrResumptionTokenExpired)
+	c.Assert(*requested, DeepEquals, []string{""})
+}
+
+func (s *iteratorSuite) TestResumptionTokenExpired(c *C) {
+	now := time.Date(2016, 3, 28, 0, 0, 0, 0, time.UTC)
+
+	c.Assert(ResumptionToken{}.Expired(now), Equals, false)
+	c.Assert(ResumptionToken{ExpirationDate: "bogus"}.Expired(now), Equals, false)
+	c.Assert(ResumptionToken{ExpirationDate: "2016-03-27T18:20:04Z"}.Expired(now), Equals, true)
+	c.Assert(ResumptionToken{ExpirationDate: "2016-03-28T18:20:04Z"}.Expired(now), Equals, false)
+}
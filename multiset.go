@@ -0,0 +1,143 @@
+package oaipmh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SetRecord is a record merged from several set harvests. SeenIn lists the
+// requested setSpecs it was returned under, in the order they were harvested.
+type SetRecord struct {
+	Record Record
+	SeenIn []string
+}
+
+// setEntry is what is kept of an identifier between the two passes of a
+// multi-set harvest.
+type setEntry struct {
+	datestamp Datestamp
+	seenIn    []string
+	handled   bool
+}
+
+func (c *Client) HarvestSets(options ListOptions, sets []string, handle func(SetRecord) error, iterateOptions ...IterateOption) error {
+	return c.HarvestSetsContext(context.Background(), options, sets, handle, iterateOptions...)
+}
+
+// HarvestSetsContext merges a harvest of several sets, handing over one
+// record per identifier with the newest datestamp. Only headers are held: a
+// ListIdentifiers pass over every set finds the newest datestamp of each
+// identifier and the sets it is in, then a ListRecords pass hands over the
+// first copy of each that is at least that new.
+func (c *Client) HarvestSetsContext(ctx context.Context, options ListOptions, sets []string, handle func(SetRecord) error, iterateOptions ...IterateOption) error {
+	if len(sets) == 0 {
+		return errors.New("At least one set must be given")
+	}
+
+	distinct := []string{}
+
+	for _, set := range sets {
+		if !containsString(distinct, set) {
+			distinct = append(distinct, set)
+		}
+	}
+
+	index, err := c.indexSets(ctx, options, distinct, iterateOptions)
+
+	if err != nil {
+		return err
+	}
+
+	for _, set := range distinct {
+		setOptions := options
+		setOptions.Set = set
+		records := c.IterateRecordsContext(ctx, setOptions, iterateOptions...)
+
+		for records.Next() {
+			record := records.Record()
+			entry, indexed := index[record.Header.Identifier]
+
+			if !indexed {
+				entry = &setEntry{datestamp: record.Header.Datestamp, seenIn: []string{set}}
+				index[record.Header.Identifier] = entry
+			}
+
+			if entry.handled || newer(entry.datestamp, record.Header.Datestamp) {
+				continue
+			}
+
+			entry.handled = true
+
+			if err := handle(SetRecord{Record: record, SeenIn: entry.seenIn}); err != nil {
+				records.Close()
+				return err
+			}
+		}
+
+		if err := records.Err(); err != nil {
+			return fmt.Errorf("Harvest of set %q failed: %w", set, err)
+		}
+	}
+
+	return nil
+}
+
+// indexSets lists the identifiers in each set, keeping the newest datestamp
+// seen for each and the sets it was seen in.
+func (c *Client) indexSets(ctx context.Context, options ListOptions, sets []string, iterateOptions []IterateOption) (map[string]*setEntry, error) {
+	index := map[string]*setEntry{}
+
+	for _, set := range sets {
+		setOptions := options
+		setOptions.Set = set
+		headers := c.IterateIdentifiersContext(ctx, setOptions, iterateOptions...)
+
+		for headers.Next() {
+			header := headers.Header()
+			entry, seen := index[header.Identifier]
+
+			if !seen {
+				index[header.Identifier] = &setEntry{datestamp: header.Datestamp, seenIn: []string{set}}
+				continue
+			}
+
+			if newer(header.Datestamp, entry.datestamp) {
+				entry.datestamp = header.Datestamp
+			}
+
+			if !containsString(entry.seenIn, set) {
+				entry.seenIn = append(entry.seenIn, set)
+			}
+		}
+
+		if err := headers.Err(); err != nil {
+			return nil, fmt.Errorf("Harvest of set %q failed: %w", set, err)
+		}
+	}
+
+	return index, nil
+}
+
+// newer reports whether a is later than b, treating unparseable datestamps
+// as older than any valid one.
+func newer(a, b Datestamp) bool {
+	at, aErr := a.Time()
+	bt, bErr := b.Time()
+
+	if aErr != nil {
+		return false
+	}
+
+	return bErr != nil || at.After(bt)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
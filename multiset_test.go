@@ -0,0 +1,117 @@
+package oaipmh
+
+import (
+	"errors"
+	. "gopkg.in/check.v1"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+)
+
+type multisetSuite struct{}
+
+var _ = Suite(&multisetSuite{})
+
+// mockSetsClient serves each set as a ListRecords page and, with the
+// metadata left out, as a ListIdentifiers page.
+func mockSetsClient(pages map[string]string) (*httptest.Server, *Client, *[]string) {
+	return mockQueryClient("set", func(query url.Values) (int, string) {
+		page := pages[query.Get("set")]
+
+		if query.Get("verb") == "ListIdentifiers" {
+			page = strings.NewReplacer("<ListRecords>", "<ListIdentifiers>", "</ListRecords>", "</ListIdentifiers>", "<record>", "", "</record>", "").Replace(page)
+		}
+
+		return http.StatusOK, page
+	})
+}
+
+func collectSets(client *Client, sets []string) ([]SetRecord, error) {
+	records := []SetRecord{}
+	err := client.HarvestSets(ListOptions{MetadataPrefix: "oai_dc"}, sets, func(record SetRecord) error {
+		records = append(records, record)
+		return nil
+	})
+
+	return records, err
+}
+
+func (s *multisetSuite) TestSetsAreMergedByIdentifier(c *C) {
+	server, client, requested := mockSetsClient(map[string]string{
+		"math": `<ListRecords>
+		  <record><header><identifier>a</identifier><datestamp>2016-03-01</datestamp></header></record>
+		  <record><header><identifier>b</identifier><datestamp>2016-03-05</datestamp></header></record>
+		</ListRecords>`,
+		"physics": `<ListRecords>
+		  <record><header><identifier>b</identifier><datestamp>2016-03-02</datestamp></header></record>
+		  <record><header><identifier>c</identifier><datestamp>2016-03-03</datestamp></header></record>
+		</ListRecords>`,
+		"cs": `<ListRecords>
+		  <record><header><identifier>a</identifier><datestamp>2016-03-09</datestamp></header></record>
+		</ListRecords>`,
+	})
+	defer server.Close()
+
+	records, err := collectSets(client, []string{"math", "physics", "math", "cs"})
+
+	c.Assert(err, IsNil)
+	c.Assert(*requested, DeepEquals, []string{"math", "physics", "cs", "math", "physics", "cs"})
+	c.Assert(records, HasLen, 3)
+	c.Assert(records[0].Record.Header.Identifier, Equals, "b")
+	c.Assert(records[0].Record.Header.Datestamp, Equals, Datestamp("2016-03-05"))
+	c.Assert(records[0].SeenIn, DeepEquals, []string{"math", "physics"})
+	c.Assert(records[1].Record.Header.Identifier, Equals, "c")
+	c.Assert(records[1].SeenIn, DeepEquals, []string{"physics"})
+	c.Assert(records[2].Record.Header.Identifier, Equals, "a")
+	c.Assert(records[2].Record.Header.Datestamp, Equals, Datestamp("2016-03-09"))
+	c.Assert(records[2].SeenIn, DeepEquals, []string{"math", "cs"})
+}
+
+func (s *multisetSuite) TestEmptySetsAreSkippedAndFailuresNamed(c *C) {
+	server, client, _ := mockSetsClient(map[string]string{
+		"empty":  `<error code="noRecordsMatch">No matching records</error>`,
+		"math":   `<ListRecords><record><header><identifier>a</identifier></header></record></ListRecords>`,
+		"broken": `<error code="badArgument">Unknown set</error>`,
+	})
+	defer server.Close()
+
+	records, err := collectSets(client, []string{"empty", "math", "broken"})
+
+	c.Assert(records, HasLen, 0)
+	c.Assert(err, ErrorMatches, `Harvest of set "broken" failed: .*`)
+	c.Assert(errors.Is(err, ErrBadArgument), Equals, true)
+}
+
+func (s *multisetSuite) TestHandlerErrorsEndTheHarvest(c *C) {
+	server, client, _ := mockSetsClient(map[string]string{
+		"math": `<ListRecords>
+		  <record><header><identifier>a</identifier></header></record>
+		  <record><header><identifier>b</identifier></header></record>
+		</ListRecords>`,
+	})
+	defer server.Close()
+
+	stop := errors.New("Stop")
+	handled := 0
+	err := client.HarvestSets(ListOptions{MetadataPrefix: "oai_dc"}, []string{"math"}, func(record SetRecord) error {
+		handled++
+		return stop
+	})
+
+	c.Assert(err, Equals, stop)
+	c.Assert(handled, Equals, 1)
+}
+
+func (s *multisetSuite) TestSetsAreRequired(c *C) {
+	err := (&Client{}).HarvestSets(ListOptions{}, nil, func(SetRecord) error { return nil })
+
+	c.Assert(err, ErrorMatches, "At least one set must be given")
+}
+
+func (s *multisetSuite) TestNewerPrefersValidDatestamps(c *C) {
+	c.Assert(newer("2016-03-02", "2016-03-01"), Equals, true)
+	c.Assert(newer("2016-03-01", "2016-03-01T10:00:00Z"), Equals, false)
+	c.Assert(newer("2016-03-01", ""), Equals, true)
+	c.Assert(newer("", "2016-03-01"), Equals, false)
+}
@@ -0,0 +1,135 @@
+package oaipmh
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"time"
+)
+
+type Option func(*Client) error
+
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) error {
+		if client == nil {
+			return errors.New("HTTP client must not be nil")
+		}
+
+		c.http = client
+
+		return nil
+	}
+}
+
+// WithTransport leaves any client passed to WithHTTPClient untouched, the
+// transport is applied to a copy of it.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) error {
+		if transport == nil {
+			return errors.New("Transport must not be nil")
+		}
+
+		c.transport = transport
+
+		return nil
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) error {
+		c.userAgent = userAgent
+
+		return nil
+	}
+}
+
+func WithFrom(email string) Option {
+	return func(c *Client) error {
+		address, err := mail.ParseAddress(email)
+
+		if err != nil {
+			return fmt.Errorf("Invalid From address %q: %v", email, err)
+		}
+
+		c.from = address.Address
+
+		return nil
+	}
+}
+
+// WithTimeout bounds each request, including ones made with a context that
+// carries no deadline of its own.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) error {
+		if timeout < 0 {
+			return errors.New("Timeout must not be negative")
+		}
+
+		c.timeout = timeout
+
+		return nil
+	}
+}
+
+// WithGranularity skips learning the datestamp granularity from Identify.
+func WithGranularity(granularity Granularity) Option {
+	return func(c *Client) error {
+		if granularity != GranularityDay && granularity != GranularitySecond {
+			return fmt.Errorf("Unsupported granularity %q", granularity)
+		}
+
+		c.granularity = granularity
+		c.configured = true
+
+		return nil
+	}
+}
+
+// WithDeletedRecords controls whether deleted records are kept as tombstones,
+// by iterators and by record slices whose elements carry the header such as
+// TypedRecord, or skipped. Slices of plain metadata types always skip them;
+// ListRecordsResponse.DeletedHeaders lists what was left out.
+func WithDeletedRecords(handling DeletedRecordHandling) Option {
+	return func(c *Client) error {
+		c.deleted = handling
+
+		return nil
+	}
+}
+
+// WithPost sends every verb as a form-encoded POST rather than a GET.
+func WithPost() Option {
+	return func(c *Client) error {
+		c.post = true
+
+		return nil
+	}
+}
+
+// WithPostThreshold switches to POST only for requests whose GET URL would be
+// longer than the given number of bytes.
+func WithPostThreshold(length int) Option {
+	return func(c *Client) error {
+		if length <= 0 {
+			return errors.New("POST threshold must be positive")
+		}
+
+		c.postAbove = length
+
+		return nil
+	}
+}
+
+// WithCompression fixes the encodings offered to the repository, which
+// otherwise come from the compression it lists in Identify. With none given,
+// responses are left to the transport and no Identify is needed.
+func WithCompression(encodings ...string) Option {
+	return func(c *Client) error {
+		c.encodings = acceptedEncodings(encodings)
+		c.compressed = true
+		c.negotiated = true
+
+		return nil
+	}
+}
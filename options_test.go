@@ -0,0 +1,147 @@
+package oaipmh
+
+import (
+	"context"
+	"errors"
+	. "gopkg.in/check.v1"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+type optionsSuite struct{}
+
+var _ = Suite(&optionsSuite{})
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func (s *optionsSuite) TestNewClientRejectsMalformedBaseURLs(c *C) {
+	for _, baseURL := range []string{"", "::", "eprints.ecs.soton.ac.uk/cgi/oai2", "ftp://example.org/oai", "http:///oai", "http://example.org/oai?verb=Identify"} {
+		client, err := NewClient(baseURL)
+
+		c.Assert(client, IsNil, Commentf(baseURL))
+		c.Assert(err, NotNil, Commentf(baseURL))
+	}
+}
+
+func (s *optionsSuite) TestNewClientReturnsOptionErrors(c *C) {
+	client, err := NewClient("http://example.org/oai", WithFrom("not an address"))
+
+	c.Assert(client, IsNil)
+	c.Assert(err, ErrorMatches, "Invalid From address .*")
+}
+
+func (s *optionsSuite) TestUserAgentAndFromHeadersAreSent(c *C) {
+	var header http.Header
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, WithUserAgent("harvester/1.0"), WithFrom("Admin <admin@example.org>"))
+	c.Assert(err, IsNil)
+	client.Identify()
+
+	c.Assert(header.Get("User-Agent"), Equals, "harvester/1.0")
+	c.Assert(header.Get("From"), Equals, "admin@example.org")
+}
+
+func (s *optionsSuite) TestTransportIsUsedWithoutModifyingSuppliedClient(c *C) {
+	requests := 0
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return nil, errors.New("intercepted")
+	})
+	supplied := &http.Client{}
+
+	client, err := NewClient("http://example.org/oai", WithHTTPClient(supplied), WithTransport(transport), WithRetryPolicy(NoRetryPolicy))
+	c.Assert(err, IsNil)
+	_, _, err = client.Identify()
+
+	c.Assert(err, ErrorMatches, ".*intercepted")
+	c.Assert(requests, Equals, 1)
+	c.Assert(supplied.Transport, IsNil)
+}
+
+func (s *optionsSuite) TestHTTPClientIsUsed(c *C) {
+	requests := 0
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return nil, errors.New("intercepted")
+	})
+
+	client, err := NewClient("http://example.org/oai", WithHTTPClient(&http.Client{Transport: transport}), WithRetryPolicy(NoRetryPolicy))
+	c.Assert(err, IsNil)
+	client.Identify()
+
+	c.Assert(requests, Equals, 1)
+}
+
+func (s *optionsSuite) TestTimeoutBoundsRequests(c *C) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := NewClient(server.URL, WithTimeout(50*time.Millisecond), WithRetryPolicy(NoRetryPolicy))
+	c.Assert(err, IsNil)
+	_, _, err = client.Identify()
+
+	c.Assert(errors.Is(err, context.DeadlineExceeded), Equals, true)
+}
+
+func (s *optionsSuite) TestPostSendsFormEncodedArguments(c *C) {
+	methods, forms := []string{}, []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		methods = append(methods, r.Method)
+		forms = append(forms, r.PostForm.Encode())
+
+		if len(methods) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, WithPost(), WithRetryPolicy(fastRetryPolicy), WithCompression())
+	c.Assert(err, IsNil)
+	client.GetRecord(&GetRecordOptions{Identifier: "oai:example.org:1", MetadataPrefix: "oai_dc"}, &DublinCoreRecord{})
+
+	expected := "identifier=oai%3Aexample.org%3A1&metadataPrefix=oai_dc&verb=GetRecord"
+
+	c.Assert(methods, DeepEquals, []string{http.MethodPost, http.MethodPost})
+	c.Assert(forms, DeepEquals, []string{expected, expected})
+}
+
+func (s *optionsSuite) TestPostThresholdOnlyAppliesToLongRequests(c *C) {
+	methods := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, WithPostThreshold(len(server.URL)+60), WithCompression())
+	c.Assert(err, IsNil)
+	client.ListIdentifiers(&ListOptions{ResumptionToken: "short"})
+	client.ListIdentifiers(&ListOptions{ResumptionToken: "a-much-longer-token-that-crosses-the-threshold"})
+
+	c.Assert(methods, DeepEquals, []string{http.MethodGet, http.MethodPost})
+}
+
+func (s *optionsSuite) TestPostThresholdMustBePositive(c *C) {
+	_, err := NewClient("http://example.org/oai", WithPostThreshold(0))
+
+	c.Assert(err, ErrorMatches, "POST threshold must be positive")
+}
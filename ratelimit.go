@@ -0,0 +1,134 @@
+package oaipmh
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit allows Rate requests per second on average, with up to Burst sent
+// back to back. A zero Rate is unlimited.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimiter is a token bucket per repository host. A single limiter can be
+// shared by any number of Clients and goroutines, so that everything sent to
+// one host draws on the same budget.
+type RateLimiter struct {
+	mu      sync.Mutex
+	limit   RateLimit
+	hosts   map[string]RateLimit
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		hosts:   map[string]RateLimit{},
+		buckets: map[string]*bucket{},
+	}
+}
+
+// SetHostLimit overrides the default limit for one host.
+func (l *RateLimiter) SetHostLimit(host string, limit RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	host = strings.ToLower(host)
+	l.hosts[host] = limit
+	delete(l.buckets, host)
+}
+
+// Wait blocks until a request to the host is allowed, or the context is done.
+func (l *RateLimiter) Wait(ctx context.Context, host string) error {
+	host = strings.ToLower(host)
+	delay := l.reserve(host, time.Now())
+
+	if delay <= 0 {
+		return nil
+	}
+
+	if err := sleep(ctx, delay); err != nil {
+		l.release(host)
+		return err
+	}
+
+	return nil
+}
+
+// reserve takes a token, letting the bucket go into debt when it is empty,
+// and returns how long the caller must wait for the token to be earned.
+func (l *RateLimiter) reserve(host string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[host]
+
+	if !ok {
+		limit, ok := l.hosts[host]
+
+		if !ok {
+			limit = l.limit
+		}
+
+		if limit.Burst < 1 {
+			limit.Burst = 1
+		}
+
+		b = &bucket{limit: limit, tokens: float64(limit.Burst), last: now}
+		l.buckets[host] = b
+	}
+
+	if b.limit.Rate <= 0 {
+		return 0
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	b.last = now
+
+	if b.tokens > float64(b.limit.Burst) {
+		b.tokens = float64(b.limit.Burst)
+	}
+
+	b.tokens--
+
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.limit.Rate * float64(time.Second))
+}
+
+// release returns a reserved token that was never used.
+func (l *RateLimiter) release(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[host]; ok {
+		b.tokens++
+	}
+}
+
+// WithRateLimiter makes every attempt, retries included, wait for the
+// limiter's budget for the repository host.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(c *Client) error {
+		if limiter == nil {
+			return errors.New("Rate limiter must not be nil")
+		}
+
+		c.limiter = limiter
+
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package oaipmh
+
+import (
+	"context"
+	"errors"
+	. "gopkg.in/check.v1"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+type rateLimitSuite struct{}
+
+var _ = Suite(&rateLimitSuite{})
+
+func (s *rateLimitSuite) TestBucketAllowsBurstThenPacesRequests(c *C) {
+	limiter := NewRateLimiter(RateLimit{Rate: 2, Burst: 2})
+	now := time.Now()
+
+	c.Assert(limiter.reserve("example.org", now), Equals, time.Duration(0))
+	c.Assert(limiter.reserve("example.org", now), Equals, time.Duration(0))
+	c.Assert(limiter.reserve("example.org", now), Equals, 500*time.Millisecond)
+	c.Assert(limiter.reserve("example.org", now), Equals, time.Second)
+	c.Assert(limiter.reserve("example.org", now.Add(2*time.Second)), Equals, time.Duration(0))
+}
+
+func (s *rateLimitSuite) TestHostsHaveSeparateBuckets(c *C) {
+	limiter := NewRateLimiter(RateLimit{Rate: 1})
+	limiter.SetHostLimit("SLOW.example.org", RateLimit{Rate: 0.1})
+	now := time.Now()
+
+	limiter.reserve("one.example.org", now)
+	limiter.reserve("slow.example.org", now)
+
+	c.Assert(limiter.reserve("two.example.org", now), Equals, time.Duration(0))
+	c.Assert(limiter.reserve("one.example.org", now), Equals, time.Second)
+	c.Assert(limiter.reserve("slow.example.org", now), Equals, 10*time.Second)
+}
+
+func (s *rateLimitSuite) TestZeroRateIsUnlimited(c *C) {
+	limiter := NewRateLimiter(RateLimit{})
+
+	for i := 0; i < 10; i++ {
+		c.Assert(limiter.reserve("example.org", time.Now()), Equals, time.Duration(0))
+	}
+}
+
+func (s *rateLimitSuite) TestWaitHonoursCancellation(c *C) {
+	limiter := NewRateLimiter(RateLimit{Rate: 0.01})
+	limiter.Wait(context.Background(), "example.org")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	c.Assert(errors.Is(limiter.Wait(ctx, "example.org"), context.DeadlineExceeded), Equals, true)
+}
+
+func (s *rateLimitSuite) TestClientsShareHostBudget(c *C) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	limiter := NewRateLimiter(RateLimit{Rate: 0.01})
+	first, _ := NewClient(server.URL, WithRateLimiter(limiter))
+	second, _ := NewClient(server.URL+"/other", WithRateLimiter(limiter))
+
+	first.Identify()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err := second.IdentifyContext(ctx)
+
+	c.Assert(errors.Is(err, context.DeadlineExceeded), Equals, true)
+	c.Assert(requests, Equals, 1)
+}
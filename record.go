@@ -0,0 +1,127 @@
+package oaipmh
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+)
+
+// TypedRecord pairs a record header with its decoded metadata and the raw
+// metadata XML it was decoded from. Passing a *TypedRecord[T] to GetRecord, or
+// a struct whose Records field is a []TypedRecord[T] to ListRecords, fills in
+// the whole envelope.
+type TypedRecord[T any] struct {
+	Header   RecordHeader
+	Metadata T
+	Raw      []byte
+}
+
+type recordEnvelope interface {
+	setRecord(record Record) error
+}
+
+type DeletedRecordPolicy string
+
+const (
+	DeletedRecordNo         DeletedRecordPolicy = "no"
+	DeletedRecordTransient  DeletedRecordPolicy = "transient"
+	DeletedRecordPersistent DeletedRecordPolicy = "persistent"
+)
+
+type DeletedRecordHandling int
+
+const (
+	DeletedRecordsAsTombstones DeletedRecordHandling = iota
+	SkipDeletedRecords
+)
+
+func (h RecordHeader) IsDeleted() bool {
+	return h.Status == "deleted"
+}
+
+// DeletedHeaders returns the headers of records the repository reports as
+// deleted, which carry no metadata.
+func (r *ListRecordsResponse) DeletedHeaders() []RecordHeader {
+	headers := []RecordHeader{}
+
+	for _, record := range r.Records {
+		if record.Header.IsDeleted() {
+			headers = append(headers, record.Header)
+		}
+	}
+
+	return headers
+}
+
+// DecodeRecord decodes the metadata of a record into T. Deleted records carry
+// no metadata, so they decode to the zero value of T without error.
+func DecodeRecord[T any](record Record) (TypedRecord[T], error) {
+	typed := TypedRecord[T]{Header: record.Header, Raw: record.Metadata.Raw}
+
+	if record.Header.IsDeleted() {
+		return typed, nil
+	}
+
+	if err := xml.Unmarshal(record.Metadata.Raw, &typed.Metadata); err != nil {
+		return typed, &RecordError{Identifier: record.Header.Identifier, Err: err}
+	}
+
+	return typed, nil
+}
+
+func (r *TypedRecord[T]) setRecord(record Record) error {
+	typed, err := DecodeRecord[T](record)
+	*r = typed
+
+	return err
+}
+
+func GetRecordAs[T any](c *Client, options *GetRecordOptions) (TypedRecord[T], *GetRecordResponse, *HTTPResponse, error) {
+	return GetRecordAsContext[T](context.Background(), c, options)
+}
+
+func GetRecordAsContext[T any](ctx context.Context, c *Client, options *GetRecordOptions) (TypedRecord[T], *GetRecordResponse, *HTTPResponse, error) {
+	response, httpResponse, err := c.getRecord(ctx, options)
+
+	if err != nil {
+		return TypedRecord[T]{}, response, httpResponse, err
+	}
+
+	record, err := DecodeRecord[T](response.Record)
+
+	return record, response, httpResponse, err
+}
+
+// ListRecordsAs decodes every record on a single page. Records whose metadata
+// fails to decode are still returned with their header, and each failure is
+// reported as a RecordError within the returned error.
+func ListRecordsAs[T any](c *Client, options *ListOptions) ([]TypedRecord[T], *ListRecordsResponse, *HTTPResponse, error) {
+	return ListRecordsAsContext[T](context.Background(), c, options)
+}
+
+func ListRecordsAsContext[T any](ctx context.Context, c *Client, options *ListOptions) ([]TypedRecord[T], *ListRecordsResponse, *HTTPResponse, error) {
+	response, httpResponse, err := c.listRecords(ctx, options)
+
+	if err != nil {
+		return nil, response, httpResponse, err
+	}
+
+	records := make([]TypedRecord[T], 0, len(response.Records))
+	errs := []error{}
+
+	for _, item := range response.Records {
+		if item.Header.IsDeleted() && c.deleted == SkipDeletedRecords {
+			continue
+		}
+
+		record, err := DecodeRecord[T](item)
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, response, httpResponse, errors.Join(errs...)
+}
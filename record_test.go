@@ -0,0 +1,301 @@
+package oaipmh
+
+import (
+	"encoding/xml"
+	"errors"
+	. "gopkg.in/check.v1"
+)
+
+type recordSuite struct{}
+
+var _ = Suite(&recordSuite{})
+
+const pageWithDeletedRecord = `<ListRecords>
+  <record>
+    <header><identifier>a</identifier></header>
+    <metadata><oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>A</dc:title></oai_dc:dc></metadata>
+  </record>
+  <record>
+    <header status="deleted"><identifier>b</identifier><datestamp>2016-03-27</datestamp></header>
+  </record>
+  <record>
+    <header><identifier>c</identifier></header>
+    <metadata><oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>C</dc:title></oai_dc:dc></metadata>
+  </record>
+</ListRecords>`
+
+func (s *recordSuite) TestIsDeletedReadsStatusAttribute(c *C) {
+	header := new(RecordHeader)
+	xml.Unmarshal([]byte(`<header status="deleted"><identifier>a</identifier></header>`), header)
+
+	c.Assert(header.IsDeleted(), Equals, true)
+	c.Assert(RecordHeader{}.IsDeleted(), Equals, false)
+}
+
+func (s *recordSuite) TestDeletedRecordsAreNotDecodedIntoPlainMetadata(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{"": pageWithDeletedRecord})
+	defer server.Close()
+
+	records := new(DublinCoreRecords)
+	response, _, err := client.ListRecords(&ListOptions{MetadataPrefix: "oai_dc"}, records)
+
+	c.Assert(err, IsNil)
+	c.Assert(records.Records, HasLen, 2)
+	c.Assert(records.Records[0].Titles, DeepEquals, []string{"A"})
+	c.Assert(records.Records[1].Titles, DeepEquals, []string{"C"})
+	c.Assert(response.DeletedHeaders()[0].Identifier, Equals, "b")
+}
+
+func (s *recordSuite) TestEnvelopesCanSkipDeletedRecords(c *C) {
+	server, _, _ := mockPagedClient("resumptionToken", map[string]string{"": pageWithDeletedRecord})
+	defer server.Close()
+
+	client, _ := NewClient(server.URL, WithDeletedRecords(SkipDeletedRecords))
+	records := new(struct {
+		Records []TypedRecord[DublinCoreRecord]
+	})
+	_, _, err := client.ListRecords(&ListOptions{MetadataPrefix: "oai_dc"}, records)
+
+	c.Assert(err, IsNil)
+	c.Assert(records.Records, HasLen, 2)
+	c.Assert(records.Records[1].Header.Identifier, Equals, "c")
+}
+
+func (s *recordSuite) TestDeletedRecordsCanBeSkipped(c *C) {
+	server, _, _ := mockPagedClient("resumptionToken", map[string]string{"": pageWithDeletedRecord})
+	defer server.Close()
+
+	client, _ := NewClient(server.URL, WithDeletedRecords(SkipDeletedRecords))
+	records := new(DublinCoreRecords)
+	response, _, err := client.ListRecords(&ListOptions{MetadataPrefix: "oai_dc"}, records)
+
+	c.Assert(err, IsNil)
+	c.Assert(records.Records, HasLen, 2)
+	c.Assert(records.Records[0].Titles, DeepEquals, []string{"A"})
+	c.Assert(records.Records[1].Titles, DeepEquals, []string{"C"})
+	c.Assert(response.DeletedHeaders(), HasLen, 1)
+	c.Assert(response.DeletedHeaders()[0].Identifier, Equals, "b")
+}
+
+func (s *recordSuite) TestIteratorsCanSkipDeletedRecords(c *C) {
+	server, _, _ := mockPagedClient("resumptionToken", map[string]string{"": pageWithDeletedRecord})
+	defer server.Close()
+
+	client, _ := NewClient(server.URL, WithDeletedRecords(SkipDeletedRecords))
+	iterator := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"})
+	identifiers := []string{}
+
+	for iterator.Next() {
+		identifiers = append(identifiers, iterator.Record().Header.Identifier)
+	}
+
+	c.Assert(iterator.Err(), IsNil)
+	c.Assert(identifiers, DeepEquals, []string{"a", "c"})
+}
+
+func (s *recordSuite) TestIteratorsSurfaceDeletedRecordsByDefault(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{"": pageWithDeletedRecord})
+	defer server.Close()
+
+	iterator := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"})
+	deleted := []string{}
+
+	for iterator.Next() {
+		if iterator.Record().Header.IsDeleted() {
+			deleted = append(deleted, iterator.Record().Header.Identifier)
+		}
+	}
+
+	c.Assert(iterator.Err(), IsNil)
+	c.Assert(deleted, DeepEquals, []string{"b"})
+}
+
+func (s *recordSuite) TestGetRecordLeavesMetadataUntouchedForDeletedRecords(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<GetRecord><record><header status="deleted"><identifier>b</identifier></header></record></GetRecord>`,
+	})
+	defer server.Close()
+
+	metadata := new(DublinCoreRecord)
+	response, _, err := client.GetRecord(&GetRecordOptions{"b", "oai_dc"}, metadata)
+
+	c.Assert(err, IsNil)
+	c.Assert(response.Record.Header.IsDeleted(), Equals, true)
+	c.Assert(metadata, DeepEquals, &DublinCoreRecord{})
+}
+
+func (s *recordSuite) TestIdentifyParsesDeletedRecordPolicy(c *C) {
+	server, client, _ := mockPagedClient("verb", map[string]string{
+		"Identify": `<Identify><deletedRecord>transient</deletedRecord></Identify>`,
+	})
+	defer server.Close()
+
+	identity, _, err := client.Identify()
+
+	c.Assert(err, IsNil)
+	c.Assert(identity.Identify.DeletedRecord, Equals, DeletedRecordTransient)
+}
+
+const pageWithMalformedRecord = `<ListRecords>
+  <record>
+    <header><identifier>a</identifier></header>
+    <metadata><oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>A</dc:title></oai_dc:dc></metadata>
+  </record>
+  <record>
+    <header><identifier>b</identifier></header>
+    <metadata><marc:record xmlns:marc="http://www.loc.gov/MARC21/slim"/></metadata>
+  </record>
+</ListRecords>`
+
+func (s *recordSuite) TestListRecordsAsPairsHeadersWithMetadata(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{"": pageWithDeletedRecord})
+	defer server.Close()
+
+	records, response, _, err := ListRecordsAs[DublinCoreRecord](client, &ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(err, IsNil)
+	c.Assert(response.Records, HasLen, 3)
+	c.Assert(records, HasLen, 3)
+	c.Assert(records[0].Header.Identifier, Equals, "a")
+	c.Assert(records[0].Metadata.Titles, DeepEquals, []string{"A"})
+	c.Assert(records[1].Header.IsDeleted(), Equals, true)
+	c.Assert(records[1].Metadata, DeepEquals, DublinCoreRecord{})
+	c.Assert(records[2].Header.Identifier, Equals, "c")
+}
+
+func (s *recordSuite) TestListRecordsAsHonoursSkipDeleted(c *C) {
+	server, _, _ := mockPagedClient("resumptionToken", map[string]string{"": pageWithDeletedRecord})
+	defer server.Close()
+
+	client, _ := NewClient(server.URL, WithDeletedRecords(SkipDeletedRecords))
+	records, _, _, err := ListRecordsAs[DublinCoreRecord](client, &ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 2)
+}
+
+func (s *recordSuite) TestListRecordsAsReportsEveryDecodeError(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{"": pageWithMalformedRecord})
+	defer server.Close()
+
+	records, _, _, err := ListRecordsAs[DublinCoreRecord](client, &ListOptions{MetadataPrefix: "oai_dc"})
+
+	var recordErr *RecordError
+
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[1].Header.Identifier, Equals, "b")
+	c.Assert(errors.As(err, &recordErr), Equals, true)
+	c.Assert(recordErr.Identifier, Equals, "b")
+	c.Assert(err, ErrorMatches, "Unable to decode record b: .*")
+}
+
+func (s *recordSuite) TestListRecordsReportsDecodeErrors(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{"": pageWithMalformedRecord})
+	defer server.Close()
+
+	records := new(DublinCoreRecords)
+	_, _, err := client.ListRecords(&ListOptions{MetadataPrefix: "oai_dc"}, records)
+
+	var recordErr *RecordError
+
+	c.Assert(records.Records, HasLen, 2)
+	c.Assert(errors.As(err, &recordErr), Equals, true)
+	c.Assert(recordErr.Identifier, Equals, "b")
+}
+
+func (s *recordSuite) TestGetRecordAs(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<GetRecord><record>
+		  <header><identifier>a</identifier><datestamp>2016-03-27</datestamp></header>
+		  <metadata><oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>A</dc:title></oai_dc:dc></metadata>
+		</record></GetRecord>`,
+	})
+	defer server.Close()
+
+	record, _, _, err := GetRecordAs[DublinCoreRecord](client, &GetRecordOptions{"a", "oai_dc"})
+
+	c.Assert(err, IsNil)
+	c.Assert(record.Header.Datestamp, Equals, Datestamp("2016-03-27"))
+	c.Assert(record.Metadata.Titles, DeepEquals, []string{"A"})
+}
+
+func (s *recordSuite) TestGetRecordAsReturnsProtocolErrors(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<error code="idDoesNotExist">No such record</error>`,
+	})
+	defer server.Close()
+
+	_, _, _, err := GetRecordAs[DublinCoreRecord](client, &GetRecordOptions{"x", "oai_dc"})
+
+	c.Assert(errors.Is(err, ErrIDDoesNotExist), Equals, true)
+}
+
+func (s *recordSuite) TestDecodeRecordFromIterator(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{"": pageWithDeletedRecord})
+	defer server.Close()
+
+	iterator := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"})
+	c.Assert(iterator.Next(), Equals, true)
+	record, err := DecodeRecord[DublinCoreRecord](iterator.Record())
+	iterator.Close()
+
+	c.Assert(err, IsNil)
+	c.Assert(record.Header.Identifier, Equals, "a")
+	c.Assert(record.Metadata.Titles, DeepEquals, []string{"A"})
+}
+
+func (s *recordSuite) TestListRecordsFillsRecordEnvelopes(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{"": pageWithDeletedRecord})
+	defer server.Close()
+
+	records := new(struct {
+		Records []TypedRecord[DublinCoreRecord]
+	})
+	_, _, err := client.ListRecords(&ListOptions{MetadataPrefix: "oai_dc"}, records)
+
+	c.Assert(err, IsNil)
+	c.Assert(records.Records, HasLen, 3)
+	c.Assert(records.Records[0].Header.Identifier, Equals, "a")
+	c.Assert(records.Records[0].Metadata.Titles, DeepEquals, []string{"A"})
+	c.Assert(string(records.Records[0].Raw), Matches, `<oai_dc:dc .*<dc:title>A</dc:title></oai_dc:dc>`)
+	c.Assert(records.Records[1].Header.Identifier, Equals, "b")
+	c.Assert(records.Records[1].Header.IsDeleted(), Equals, true)
+	c.Assert(records.Records[1].Raw, HasLen, 0)
+	c.Assert(records.Records[2].Header.Identifier, Equals, "c")
+}
+
+func (s *recordSuite) TestListRecordsReportsEnvelopeDecodeErrors(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{"": pageWithMalformedRecord})
+	defer server.Close()
+
+	records := new(struct {
+		Records []TypedRecord[DublinCoreRecord]
+	})
+	_, _, err := client.ListRecords(&ListOptions{MetadataPrefix: "oai_dc"}, records)
+
+	var recordErr *RecordError
+
+	c.Assert(errors.As(err, &recordErr), Equals, true)
+	c.Assert(recordErr.Identifier, Equals, "b")
+	c.Assert(records.Records[1].Header.Identifier, Equals, "b")
+	c.Assert(string(records.Records[1].Raw), Matches, `<marc:record .*/>`)
+}
+
+func (s *recordSuite) TestGetRecordFillsRecordEnvelope(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<GetRecord><record>
+		  <header><identifier>a</identifier><setSpec>math</setSpec></header>
+		  <metadata><oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>A</dc:title></oai_dc:dc></metadata>
+		</record></GetRecord>`,
+	})
+	defer server.Close()
+
+	record := new(TypedRecord[DublinCoreRecord])
+	_, _, err := client.GetRecord(&GetRecordOptions{"a", "oai_dc"}, record)
+
+	c.Assert(err, IsNil)
+	c.Assert(record.Header.Identifier, Equals, "a")
+	c.Assert(record.Header.SetSpec, DeepEquals, []string{"math"})
+	c.Assert(record.Metadata.Titles, DeepEquals, []string{"A"})
+	c.Assert(record.Raw, NotNil)
+}
@@ -0,0 +1,165 @@
+package oaipmh
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried. Responses with status
+// 429 or 5xx, and transport errors, are retried up to MaxAttempts in total. A
+// Retry-After header takes precedence over the exponential backoff, which
+// starts at BaseDelay and is capped at MaxDelay.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   time.Second,
+	MaxDelay:    time.Minute,
+}
+
+var NoRetryPolicy = RetryPolicy{MaxAttempts: 1}
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) error {
+		if policy.MaxAttempts < 1 {
+			return errors.New("Retry policy must allow at least one attempt")
+		}
+
+		if policy.BaseDelay < 0 || policy.MaxDelay < 0 {
+			return errors.New("Retry policy delays must not be negative")
+		}
+
+		c.retry = policy
+
+		return nil
+	}
+}
+
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter >= 0 {
+		return retryAfter
+	}
+
+	return p.backoff(attempt)
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+
+	for i := 1; i < attempt && (p.MaxDelay == 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// retryable reports whether a failed attempt is worth repeating. Only status
+// 429 and 5xx responses, and transport failures other than certificate
+// verification, are; anything else would fail the same way again.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var httpErr *HTTPError
+
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
+
+	var authErr *authenticationError
+	var urlErr *url.Error
+	var netErr net.Error
+
+	if errors.As(err, &authErr) || (!errors.As(err, &urlErr) && !errors.As(err, &netErr)) {
+		return false
+	}
+
+	return !certificateError(err)
+}
+
+func certificateError(err error) bool {
+	var verification *tls.CertificateVerificationError
+	var authority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+
+	return errors.As(err, &verification) || errors.As(err, &authority) || errors.As(err, &hostname) || errors.As(err, &invalid)
+}
+
+func retryAfter(err error) time.Duration {
+	if httpErr, ok := err.(*HTTPError); ok {
+		return httpErr.retryAfter
+	}
+
+	return -1
+}
+
+// parseRetryAfter accepts both the delta-seconds and HTTP-date forms, returning
+// -1 when the header is absent or unparseable.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		return -1
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return -1
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	at, err := http.ParseTime(value)
+
+	if err != nil {
+		return -1
+	}
+
+	if delay := at.Sub(now); delay > 0 {
+		return delay
+	}
+
+	return 0
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,193 @@
+package oaipmh
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	. "gopkg.in/check.v1"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+)
+
+type retrySuite struct{}
+
+var _ = Suite(&retrySuite{})
+
+var fastRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func mockFlakyClient(responses []int, retryAfter string, policy RetryPolicy) (*httptest.Server, *Client, *int) {
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := responses[len(responses)-1]
+
+		if requests < len(responses) {
+			code = responses[requests]
+		}
+
+		requests++
+
+		if code != http.StatusOK {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+
+			w.WriteHeader(code)
+			return
+		}
+
+		fmt.Fprintf(w, pageTemplate, `<Identify><repositoryName>Test</repositoryName></Identify>`)
+	}))
+
+	client, _ := NewClient(server.URL, WithRetryPolicy(policy), WithCompression())
+
+	return server, client, &requests
+}
+
+func (s *retrySuite) TestServiceUnavailableIsRetried(c *C) {
+	server, client, requests := mockFlakyClient([]int{503, 503, 200}, "0", fastRetryPolicy)
+	defer server.Close()
+
+	identity, httpResponse, err := client.Identify()
+
+	c.Assert(err, IsNil)
+	c.Assert(*requests, Equals, 3)
+	c.Assert(httpResponse.StatusCode, Equals, 200)
+	c.Assert(identity.Identify.RepositoryName, Equals, "Test")
+}
+
+func (s *retrySuite) TestServerErrorsAreRetriedWithBackoff(c *C) {
+	server, client, requests := mockFlakyClient([]int{500, 502, 200}, "", fastRetryPolicy)
+	defer server.Close()
+
+	_, _, err := client.Identify()
+
+	c.Assert(err, IsNil)
+	c.Assert(*requests, Equals, 3)
+}
+
+func (s *retrySuite) TestAttemptsAreCapped(c *C) {
+	server, client, requests := mockFlakyClient([]int{503}, "0", fastRetryPolicy)
+	defer server.Close()
+
+	_, httpResponse, err := client.Identify()
+
+	c.Assert(err, NotNil)
+	c.Assert(*requests, Equals, 3)
+	c.Assert(httpResponse.StatusCode, Equals, 503)
+}
+
+func (s *retrySuite) TestClientErrorsAreNotRetried(c *C) {
+	server, client, requests := mockFlakyClient([]int{404}, "", fastRetryPolicy)
+	defer server.Close()
+
+	_, _, err := client.Identify()
+
+	c.Assert(err, NotNil)
+	c.Assert(*requests, Equals, 1)
+}
+
+func (s *retrySuite) TestRetryPolicyAppliesToListVerbs(c *C) {
+	server, client, requests := mockFlakyClient([]int{503, 200}, "0", fastRetryPolicy)
+	defer server.Close()
+
+	_, _, err := client.ListSets(&ListSetsOptions{})
+
+	c.Assert(err, IsNil)
+	c.Assert(*requests, Equals, 2)
+}
+
+func (s *retrySuite) TestNetworkErrorsAreRetried(c *C) {
+	requests := 0
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return nil, errors.New("connection reset")
+	})
+
+	client, _ := NewClient("http://example.org/oai", WithTransport(transport), WithRetryPolicy(fastRetryPolicy))
+	_, _, err := client.Identify()
+
+	c.Assert(err, ErrorMatches, ".*connection reset")
+	c.Assert(requests, Equals, 3)
+}
+
+func (s *retrySuite) TestOnlyTransportErrorsAreRetryable(c *C) {
+	ctx := context.Background()
+	transport := &url.Error{Op: "Get", URL: "http://example.org/oai", Err: errors.New("connection reset")}
+	certificate := &url.Error{Op: "Get", URL: "https://example.org/oai", Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}
+
+	c.Assert(retryable(ctx, transport), Equals, true)
+	c.Assert(retryable(ctx, certificate), Equals, false)
+	c.Assert(retryable(ctx, &url.Error{Op: "Get", URL: "https://example.org/oai", Err: x509.HostnameError{}}), Equals, false)
+	c.Assert(retryable(ctx, errors.New("Request could not be built")), Equals, false)
+	c.Assert(retryable(ctx, &authenticationError{transport}), Equals, false)
+}
+
+func (s *retrySuite) TestAuthenticatorErrorsAreNotRetried(c *C) {
+	fetched := 0
+	token := NewRefreshingBearerToken(func(ctx context.Context) (string, time.Time, error) {
+		fetched++
+		return "", time.Time{}, errors.New("Token service unavailable")
+	})
+	client, _ := NewClient("http://example.org/oai", WithAuthenticator(token), WithRetryPolicy(fastRetryPolicy))
+	_, _, err := client.Identify()
+
+	c.Assert(err, ErrorMatches, "Token service unavailable")
+	c.Assert(fetched, Equals, 1)
+}
+
+func (s *retrySuite) TestWaitingForRetryHonoursContext(c *C) {
+	server, client, requests := mockFlakyClient([]int{503}, "3600", fastRetryPolicy)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, err := client.IdentifyContext(ctx)
+
+	c.Assert(errors.Is(err, context.DeadlineExceeded), Equals, true)
+	c.Assert(*requests, Equals, 1)
+}
+
+func (s *retrySuite) TestWithRetryPolicyRejectsInvalidPolicies(c *C) {
+	_, err := NewClient("http://example.org/oai", WithRetryPolicy(RetryPolicy{}))
+	c.Assert(err, NotNil)
+
+	_, err = NewClient("http://example.org/oai", WithRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: -1}))
+	c.Assert(err, NotNil)
+}
+
+func (s *retrySuite) TestParseRetryAfter(c *C) {
+	now := time.Date(2016, 3, 27, 18, 20, 0, 0, time.UTC)
+
+	c.Assert(parseRetryAfter("", now), Equals, time.Duration(-1))
+	c.Assert(parseRetryAfter("120", now), Equals, 2*time.Minute)
+	c.Assert(parseRetryAfter(" 5 ", now), Equals, 5*time.Second)
+	c.Assert(parseRetryAfter("-5", now), Equals, time.Duration(-1))
+	c.Assert(parseRetryAfter("Sun, 27 Mar 2016 18:21:30 GMT", now), Equals, 90*time.Second)
+	c.Assert(parseRetryAfter("Sun, 27 Mar 2016 18:00:00 GMT", now), Equals, time.Duration(0))
+	c.Assert(parseRetryAfter("soon", now), Equals, time.Duration(-1))
+}
+
+func (s *retrySuite) TestBackoffGrowsExponentiallyWithinBounds(c *C) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	for i := 0; i < 50; i++ {
+		first := policy.backoff(1)
+		c.Assert(first >= 500*time.Millisecond && first <= time.Second, Equals, true)
+
+		third := policy.backoff(3)
+		c.Assert(third >= 2*time.Second && third <= 4*time.Second, Equals, true)
+
+		capped := policy.backoff(9)
+		c.Assert(capped >= 5*time.Second && capped <= 10*time.Second, Equals, true)
+	}
+}
+
+func (s *retrySuite) TestRetryAfterTakesPrecedenceOverBackoff(c *C) {
+	c.Assert(DefaultRetryPolicy.delay(1, 3*time.Second), Equals, 3*time.Second)
+	c.Assert(DefaultRetryPolicy.delay(1, 0), Equals, time.Duration(0))
+}
@@ -0,0 +1,105 @@
+package oaipmh
+
+import (
+	"context"
+	"strings"
+)
+
+const dublinCoreNamespace = "http://www.openarchives.org/OAI/2.0/oai_dc/"
+
+// SetNode is one level of the setSpec hierarchy. Ancestors the repository
+// does not list itself are filled in with only their SetSpec.
+type SetNode struct {
+	Set      Set
+	Children SetTree
+}
+
+type SetTree []*SetNode
+
+// DublinCore decodes the first oai_dc set description, returning nil when
+// there is none.
+func (s Set) DublinCore() (*DublinCoreRecord, error) {
+	containers := make([][]byte, len(s.Descriptions))
+
+	for i, description := range s.Descriptions {
+		containers[i] = description.Raw
+	}
+
+	record := new(DublinCoreRecord)
+	found, err := decodeContainer(containers, dublinCoreNamespace, record)
+
+	if !found {
+		return nil, err
+	}
+
+	return record, err
+}
+
+func (c *Client) SetTree() (SetTree, error) {
+	return c.SetTreeContext(context.Background())
+}
+
+// SetTreeContext harvests every set and arranges them by setSpec. A repository
+// without set support yields an empty tree.
+func (c *Client) SetTreeContext(ctx context.Context) (SetTree, error) {
+	sets := []Set{}
+	it := c.IterateSetsContext(ctx, ListSetsOptions{})
+
+	for it.Next() {
+		sets = append(sets, it.Set())
+	}
+
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	return BuildSetTree(sets), nil
+}
+
+// BuildSetTree arranges sets by their colon-delimited setSpec, keeping the
+// order in which they were listed.
+func BuildSetTree(sets []Set) SetTree {
+	tree := SetTree{}
+	nodes := map[string]*SetNode{}
+
+	var node func(spec string) *SetNode
+
+	node = func(spec string) *SetNode {
+		if existing, ok := nodes[spec]; ok {
+			return existing
+		}
+
+		created := &SetNode{Set: Set{SetSpec: spec}}
+		nodes[spec] = created
+
+		if i := strings.LastIndex(spec, ":"); i >= 0 {
+			parent := node(spec[:i])
+			parent.Children = append(parent.Children, created)
+		} else {
+			tree = append(tree, created)
+		}
+
+		return created
+	}
+
+	for _, set := range sets {
+		node(set.SetSpec).Set = set
+	}
+
+	return tree
+}
+
+// Find returns the node for a setSpec, or nil when it is not in the tree.
+func (t SetTree) Find(spec string) *SetNode {
+	for _, node := range t {
+		if node.Set.SetSpec == spec {
+			return node
+		}
+
+		if strings.HasPrefix(spec, node.Set.SetSpec+":") {
+			return node.Children.Find(spec)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package oaipmh
+
+import (
+	"encoding/xml"
+	. "gopkg.in/check.v1"
+)
+
+type setsSuite struct{}
+
+var _ = Suite(&setsSuite{})
+
+func (s *setsSuite) TestSetDescriptionDecodesDublinCore(c *C) {
+	set := Set{}
+	err := xml.Unmarshal([]byte(`<set>
+  <setSpec>math</setSpec>
+  <setName>Mathematics</setName>
+  <setDescription>
+    <oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+      <dc:description>Papers in mathematics</dc:description>
+    </oai_dc:dc>
+  </setDescription>
+</set>`), &set)
+
+	c.Assert(err, IsNil)
+
+	dc, err := set.DublinCore()
+
+	c.Assert(err, IsNil)
+	c.Assert(dc.Descriptions, DeepEquals, []string{"Papers in mathematics"})
+}
+
+func (s *setsSuite) TestSetWithoutDescription(c *C) {
+	dc, err := Set{SetSpec: "math"}.DublinCore()
+
+	c.Assert(err, IsNil)
+	c.Assert(dc, IsNil)
+}
+
+func (s *setsSuite) TestBuildSetTree(c *C) {
+	tree := BuildSetTree([]Set{
+		{SetSpec: "math", SetName: "Mathematics"},
+		{SetSpec: "math:algebra", SetName: "Algebra"},
+		{SetSpec: "physics:quantum", SetName: "Quantum"},
+		{SetSpec: "math:algebra:linear", SetName: "Linear Algebra"},
+		{SetSpec: "physics", SetName: "Physics"},
+	})
+
+	c.Assert(tree, HasLen, 2)
+	c.Assert(tree[0].Set.SetName, Equals, "Mathematics")
+	c.Assert(tree[0].Children, HasLen, 1)
+	c.Assert(tree[0].Children[0].Children[0].Set.SetName, Equals, "Linear Algebra")
+	c.Assert(tree[1].Set.SetName, Equals, "Physics")
+	c.Assert(tree[1].Children[0].Set.SetSpec, Equals, "physics:quantum")
+	c.Assert(tree.Find("math:algebra").Set.SetName, Equals, "Algebra")
+	c.Assert(tree.Find("math:geometry"), IsNil)
+}
+
+func (s *setsSuite) TestBuildSetTreeFillsMissingAncestors(c *C) {
+	tree := BuildSetTree([]Set{{SetSpec: "a:b:c", SetName: "C"}})
+
+	c.Assert(tree, HasLen, 1)
+	c.Assert(tree[0].Set, DeepEquals, Set{SetSpec: "a"})
+	c.Assert(tree.Find("a:b").Set, DeepEquals, Set{SetSpec: "a:b"})
+	c.Assert(tree.Find("a:b:c").Set.SetName, Equals, "C")
+}
+
+func (s *setsSuite) TestSetTreeHarvestsEveryPage(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListSets>
+  <set><setSpec>math</setSpec><setName>Mathematics</setName></set>
+  <resumptionToken>next</resumptionToken>
+</ListSets>`,
+		"next": `<ListSets>
+  <set><setSpec>math:algebra</setSpec><setName>Algebra</setName></set>
+  <resumptionToken/>
+</ListSets>`,
+	})
+	defer server.Close()
+
+	tree, err := client.SetTree()
+
+	c.Assert(err, IsNil)
+	c.Assert(tree, HasLen, 1)
+	c.Assert(tree[0].Children[0].Set.SetName, Equals, "Algebra")
+}
+
+func (s *setsSuite) TestSetTreeIsEmptyWithoutSetHierarchy(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<error code="noSetHierarchy">Sets are not supported</error>`,
+	})
+	defer server.Close()
+
+	tree, err := client.SetTree()
+
+	c.Assert(err, IsNil)
+	c.Assert(tree, HasLen, 0)
+}
@@ -0,0 +1,222 @@
+package oaipmh
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// StateKey identifies a harvested list. BaseURL is held without userinfo so
+// that credentials are never written to a store.
+type StateKey struct {
+	BaseURL        string `json:"baseURL"`
+	Set            string `json:"set,omitempty"`
+	MetadataPrefix string `json:"metadataPrefix"`
+}
+
+// HarvestState is the high-water mark left by the last successful harvest.
+// ResponseDate comes from the repository's own clock, so it is preferred over
+// LatestDatestamp when choosing where the next harvest starts.
+type HarvestState struct {
+	ResponseDate    Datestamp `json:"responseDate,omitempty"`
+	LatestDatestamp Datestamp `json:"latestDatestamp,omitempty"`
+}
+
+type StateStore interface {
+	Load(ctx context.Context, key StateKey) (HarvestState, bool, error)
+	Save(ctx context.Context, key StateKey, state HarvestState) error
+}
+
+type MemoryStateStore struct {
+	mu     sync.Mutex
+	states map[StateKey]HarvestState
+}
+
+// FileStateStore keeps every state in a single JSON document, rewritten
+// atomically on each save.
+type FileStateStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+type fileStateEntry struct {
+	StateKey
+	HarvestState
+}
+
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{states: map[StateKey]HarvestState{}}
+}
+
+func (s *MemoryStateStore) Load(ctx context.Context, key StateKey) (HarvestState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[key]
+
+	return state, ok, nil
+}
+
+func (s *MemoryStateStore) Save(ctx context.Context, key StateKey, state HarvestState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[key] = state
+
+	return nil
+}
+
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{path: path}
+}
+
+func (s *FileStateStore) Load(ctx context.Context, key StateKey) (HarvestState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.read()
+
+	if err != nil {
+		return HarvestState{}, false, err
+	}
+
+	for _, entry := range entries {
+		if entry.StateKey == key {
+			return entry.HarvestState, true, nil
+		}
+	}
+
+	return HarvestState{}, false, nil
+}
+
+func (s *FileStateStore) Save(ctx context.Context, key StateKey, state HarvestState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.read()
+
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+
+	for i := range entries {
+		if entries[i].StateKey == key {
+			entries[i].HarvestState = state
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		entries = append(entries, fileStateEntry{key, state})
+	}
+
+	return writeJSONFile(s.path, entries)
+}
+
+func (s *FileStateStore) read() ([]fileStateEntry, error) {
+	entries := []fileStateEntry{}
+	contents, err := ioutil.ReadFile(s.path)
+
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, json.Unmarshal(contents, &entries)
+}
+
+// writeJSONFile replaces the file by renaming a temporary one over it, so a
+// crash mid-write never leaves it truncated.
+func writeJSONFile(path string, value interface{}) error {
+	contents, err := json.MarshalIndent(value, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	temp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(contents); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
+
+// resumeFrom is where a harvest following the state should start. It steps
+// back by the overlap, and always by at least one unit of the granularity,
+// so records stamped in the same instant as the last harvest are not missed.
+func (s HarvestState) resumeFrom(granularity Granularity, overlap time.Duration) (time.Time, bool) {
+	mark := s.ResponseDate
+
+	if mark.IsZero() {
+		mark = s.LatestDatestamp
+	}
+
+	t, err := mark.Time()
+
+	if mark.IsZero() || err != nil {
+		return time.Time{}, false
+	}
+
+	unit := time.Second
+
+	if granularity == GranularityDay {
+		unit = 24 * time.Hour
+	}
+
+	if overlap < unit {
+		overlap = unit
+	}
+
+	return t.Add(-overlap), true
+}
+
+// observe advances the latest datestamp seen.
+func (s *HarvestState) observe(datestamp Datestamp) {
+	seen, err := datestamp.Time()
+
+	if err != nil {
+		return
+	}
+
+	latest, err := s.LatestDatestamp.Time()
+
+	if s.LatestDatestamp.IsZero() || err != nil || seen.After(latest) {
+		s.LatestDatestamp = datestamp
+	}
+}
+
+// storeURL strips userinfo from a base URL before it is used as a key.
+func storeURL(baseURL string) string {
+	u, err := url.Parse(baseURL)
+
+	if err != nil {
+		return baseURL
+	}
+
+	u.User = nil
+
+	return u.String()
+}
@@ -0,0 +1,139 @@
+package oaipmh
+
+import (
+	"context"
+	"fmt"
+	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type stateSuite struct{}
+
+var _ = Suite(&stateSuite{})
+
+func (s *stateSuite) TestMemoryStateStore(c *C) {
+	store := NewMemoryStateStore()
+	key := StateKey{"http://example.org/oai", "math", "oai_dc"}
+	_, found, err := store.Load(context.Background(), key)
+
+	c.Assert(err, IsNil)
+	c.Assert(found, Equals, false)
+
+	store.Save(context.Background(), key, HarvestState{ResponseDate: "2016-03-27T18:20:04Z"})
+	state, found, err := store.Load(context.Background(), key)
+
+	c.Assert(err, IsNil)
+	c.Assert(found, Equals, true)
+	c.Assert(state.ResponseDate, Equals, Datestamp("2016-03-27T18:20:04Z"))
+}
+
+func (s *stateSuite) TestFileStateStorePersistsAcrossInstances(c *C) {
+	path := filepath.Join(c.MkDir(), "state.json")
+	first := StateKey{"http://example.org/oai", "", "oai_dc"}
+	second := StateKey{"http://example.org/oai", "math", "oai_dc"}
+
+	c.Assert(NewFileStateStore(path).Save(context.Background(), first, HarvestState{ResponseDate: "2016-03-01"}), IsNil)
+	c.Assert(NewFileStateStore(path).Save(context.Background(), second, HarvestState{LatestDatestamp: "2016-03-02"}), IsNil)
+	c.Assert(NewFileStateStore(path).Save(context.Background(), first, HarvestState{ResponseDate: "2016-03-03"}), IsNil)
+
+	store := NewFileStateStore(path)
+	state, found, err := store.Load(context.Background(), first)
+
+	c.Assert(err, IsNil)
+	c.Assert(found, Equals, true)
+	c.Assert(state, Equals, HarvestState{ResponseDate: "2016-03-03"})
+
+	state, _, _ = store.Load(context.Background(), second)
+
+	c.Assert(state, Equals, HarvestState{LatestDatestamp: "2016-03-02"})
+}
+
+func (s *stateSuite) TestFileStateStoreWithoutFileIsEmpty(c *C) {
+	_, found, err := NewFileStateStore(filepath.Join(c.MkDir(), "missing.json")).Load(context.Background(), StateKey{})
+
+	c.Assert(err, IsNil)
+	c.Assert(found, Equals, false)
+}
+
+func (s *stateSuite) TestResumeFromOverlapsByGranularity(c *C) {
+	state := HarvestState{ResponseDate: "2016-03-27T18:20:04Z", LatestDatestamp: "2016-03-20"}
+
+	from, ok := state.resumeFrom(GranularitySecond, 0)
+
+	c.Assert(ok, Equals, true)
+	c.Assert(from, Equals, time.Date(2016, 3, 27, 18, 20, 3, 0, time.UTC))
+
+	from, _ = state.resumeFrom(GranularityDay, time.Hour)
+
+	c.Assert(from, Equals, time.Date(2016, 3, 26, 18, 20, 4, 0, time.UTC))
+
+	from, _ = HarvestState{LatestDatestamp: "2016-03-20"}.resumeFrom(GranularitySecond, time.Minute)
+
+	c.Assert(from, Equals, time.Date(2016, 3, 19, 23, 59, 0, 0, time.UTC))
+
+	_, ok = HarvestState{}.resumeFrom(GranularitySecond, 0)
+
+	c.Assert(ok, Equals, false)
+}
+
+func (s *stateSuite) TestHarvesterResumesFromSavedState(c *C) {
+	froms := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		froms = append(froms, r.URL.Query().Get("from"))
+		fmt.Fprintf(w, pageTemplate, `<ListRecords>
+		  <record><header><identifier>a</identifier><datestamp>2016-03-25T10:00:00Z</datestamp></header></record>
+		  <record><header><identifier>b</identifier><datestamp>2016-03-26T10:00:00Z</datestamp></header></record>
+		</ListRecords>`)
+	}))
+	defer server.Close()
+
+	store := NewMemoryStateStore()
+	harvester := &Harvester{State: store, Overlap: time.Hour, ClientOptions: []Option{WithGranularity(GranularitySecond), WithCompression()}}
+	endpoints := []Endpoint{{BaseURL: server.URL, Options: ListOptions{MetadataPrefix: "oai_dc"}}}
+
+	c.Assert(harvester.Harvest(context.Background(), endpoints).Failed(), HasLen, 0)
+	c.Assert(harvester.Harvest(context.Background(), endpoints).Failed(), HasLen, 0)
+
+	state, _, _ := store.Load(context.Background(), StateKey{server.URL, "", "oai_dc"})
+
+	c.Assert(froms, DeepEquals, []string{"", "2016-03-27T17:20:04Z"})
+	c.Assert(state, Equals, HarvestState{ResponseDate: "2016-03-27T18:20:04Z", LatestDatestamp: "2016-03-26T10:00:00Z"})
+}
+
+func (s *stateSuite) TestFailedHarvestLeavesStateUntouched(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	store := NewMemoryStateStore()
+	harvester := &Harvester{State: store, ClientOptions: []Option{WithRetryPolicy(NoRetryPolicy)}}
+	report := harvester.Harvest(context.Background(), []Endpoint{{BaseURL: server.URL, Options: ListOptions{MetadataPrefix: "oai_dc"}}})
+	_, found, _ := store.Load(context.Background(), StateKey{server.URL, "", "oai_dc"})
+
+	c.Assert(report.Failed(), HasLen, 1)
+	c.Assert(found, Equals, false)
+}
+
+func (s *stateSuite) TestStateKeysExcludeCredentials(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, pageTemplate, `<ListRecords><record><header><identifier>a</identifier></header></record></ListRecords>`)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(c.MkDir(), "state.json")
+	harvester := &Harvester{State: NewFileStateStore(path)}
+	report := harvester.Harvest(context.Background(), []Endpoint{{BaseURL: strings.Replace(server.URL, "http://", "http://user:secret@", 1), Options: ListOptions{MetadataPrefix: "oai_dc"}}})
+	contents, _ := ioutil.ReadFile(path)
+	_, found, _ := NewFileStateStore(path).Load(context.Background(), StateKey{server.URL, "", "oai_dc"})
+
+	c.Assert(report.Failed(), HasLen, 0)
+	c.Assert(found, Equals, true)
+	c.Assert(strings.Contains(string(contents), "secret"), Equals, false)
+}
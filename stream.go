@@ -0,0 +1,88 @@
+package oaipmh
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/url"
+)
+
+// listStream decodes a list response one element at a time, so memory use is
+// bounded by the size of a single record rather than the whole page.
+type listStream struct {
+	body         io.ReadCloser
+	cancel       context.CancelFunc
+	decoder      *xml.Decoder
+	element      string
+	responseDate Datestamp
+	token        ResumptionToken
+	errors       Errors
+}
+
+func (c *Client) openStream(ctx context.Context, params url.Values, element string) (*listStream, error) {
+	res, cancel, err := c.open(ctx, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &listStream{
+		body:    res.Body,
+		cancel:  cancel,
+		decoder: xml.NewDecoder(res.Body),
+		element: element,
+	}, nil
+}
+
+// next decodes the next list element into the supplied value. It returns false
+// once the document is exhausted, along with any OAI-PMH errors it contained.
+func (s *listStream) next(into interface{}) (bool, error) {
+	for {
+		token, err := s.decoder.Token()
+
+		if err == io.EOF {
+			return false, s.errors.err()
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		start, ok := token.(xml.StartElement)
+
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case s.element:
+			if err := s.decoder.DecodeElement(into, &start); err != nil {
+				return false, err
+			}
+
+			return true, nil
+		case "error":
+			e := Error{}
+
+			if err := s.decoder.DecodeElement(&e, &start); err != nil {
+				return false, err
+			}
+
+			s.errors = append(s.errors, e)
+		case "responseDate":
+			if err := s.decoder.DecodeElement(&s.responseDate, &start); err != nil {
+				return false, err
+			}
+		case "resumptionToken":
+			if err := s.decoder.DecodeElement(&s.token, &start); err != nil {
+				return false, err
+			}
+		}
+	}
+}
+
+func (s *listStream) close() error {
+	defer s.cancel()
+
+	return s.body.Close()
+}
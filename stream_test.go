@@ -0,0 +1,122 @@
+package oaipmh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	. "gopkg.in/check.v1"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+)
+
+type streamSuite struct{}
+
+var _ = Suite(&streamSuite{})
+
+func (s *streamSuite) TestRecordsAreYieldedBeforeThePageCompletes(c *C) {
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/"><ListRecords>
+		  <record><header><identifier>a</identifier></header></record>`)
+		w.(http.Flusher).Flush()
+		<-release
+		fmt.Fprint(w, `<record><header><identifier>b</identifier></header></record>
+		</ListRecords></OAI-PMH>`)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(server.URL, WithCompression())
+	iterator := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(iterator.Next(), Equals, true)
+	c.Assert(iterator.Record().Header.Identifier, Equals, "a")
+	close(release)
+	c.Assert(iterator.Next(), Equals, true)
+	c.Assert(iterator.Record().Header.Identifier, Equals, "b")
+	c.Assert(iterator.Next(), Equals, false)
+	c.Assert(iterator.Err(), IsNil)
+}
+
+func (s *streamSuite) TestStreamCollectsEveryError(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<error code="badVerb">Illegal verb</error>
+		<error code="badArgument">Illegal argument</error>`,
+	})
+	defer server.Close()
+
+	stream, err := client.openStream(context.Background(), url.Values{}, "record")
+	c.Assert(err, IsNil)
+	defer stream.close()
+
+	ok, err := stream.next(new(Record))
+
+	c.Assert(ok, Equals, false)
+	c.Assert(errors.Is(err, ErrBadVerb), Equals, true)
+	c.Assert(errors.Is(err, ErrBadArgument), Equals, true)
+}
+
+func (s *streamSuite) TestStreamCapturesResponseDateAndToken(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListIdentifiers>
+		  <header><identifier>a</identifier></header>
+		  <resumptionToken cursor="0">next</resumptionToken>
+		</ListIdentifiers>`,
+	})
+	defer server.Close()
+
+	stream, err := client.openStream(context.Background(), url.Values{}, "header")
+	c.Assert(err, IsNil)
+	defer stream.close()
+
+	header := new(RecordHeader)
+	ok, err := stream.next(header)
+	c.Assert(ok, Equals, true)
+	c.Assert(header.Identifier, Equals, "a")
+
+	ok, err = stream.next(new(RecordHeader))
+	c.Assert(ok, Equals, false)
+	c.Assert(err, IsNil)
+	c.Assert(stream.responseDate, Equals, Datestamp("2016-03-27T18:20:04Z"))
+	c.Assert(stream.token.Value, Equals, "next")
+}
+
+func (s *streamSuite) TestNestedElementsWithListNamesAreNotYielded(c *C) {
+	server, client, _ := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListRecords>
+		  <record><header><identifier>a</identifier></header><metadata><record xmlns="http://www.loc.gov/MARC21/slim"><leader>x</leader></record></metadata></record>
+		  <record><header><identifier>b</identifier></header></record>
+		</ListRecords>`,
+	})
+	defer server.Close()
+
+	iterator := client.IterateRecords(ListOptions{MetadataPrefix: "marc21"})
+	identifiers := []string{}
+
+	for iterator.Next() {
+		identifiers = append(identifiers, iterator.Record().Header.Identifier)
+	}
+
+	c.Assert(iterator.Err(), IsNil)
+	c.Assert(identifiers, DeepEquals, []string{"a", "b"})
+}
+
+func (s *streamSuite) TestCloseAbandonsIteration(c *C) {
+	server, client, requested := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListRecords>
+		  <record><header><identifier>a</identifier></header></record>
+		  <record><header><identifier>b</identifier></header></record>
+		  <resumptionToken>page2</resumptionToken>
+		</ListRecords>`,
+	})
+	defer server.Close()
+
+	iterator := client.IterateRecords(ListOptions{MetadataPrefix: "oai_dc"})
+
+	c.Assert(iterator.Next(), Equals, true)
+	c.Assert(iterator.Close(), IsNil)
+	c.Assert(iterator.Next(), Equals, false)
+	c.Assert(iterator.Err(), IsNil)
+	c.Assert(*requested, DeepEquals, []string{""})
+}
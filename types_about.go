@@ -0,0 +1,37 @@
+package oaipmh
+
+import "encoding/xml"
+
+const (
+	provenanceNamespace = "http://www.openarchives.org/OAI/2.0/provenance"
+	rightsNamespace     = "http://www.openarchives.org/OAI/2.0/rights/"
+)
+
+type Provenance struct {
+	XMLName           xml.Name          `xml:"http://www.openarchives.org/OAI/2.0/provenance provenance"`
+	OriginDescription OriginDescription `xml:"http://www.openarchives.org/OAI/2.0/provenance originDescription"`
+}
+
+type OriginDescription struct {
+	HarvestDate       Datestamp          `xml:"harvestDate,attr"`
+	Altered           bool               `xml:"altered,attr"`
+	BaseURL           string             `xml:"http://www.openarchives.org/OAI/2.0/provenance baseURL"`
+	Identifier        string             `xml:"http://www.openarchives.org/OAI/2.0/provenance identifier"`
+	Datestamp         Datestamp          `xml:"http://www.openarchives.org/OAI/2.0/provenance datestamp"`
+	MetadataNamespace string             `xml:"http://www.openarchives.org/OAI/2.0/provenance metadataNamespace"`
+	OriginDescription *OriginDescription `xml:"http://www.openarchives.org/OAI/2.0/provenance originDescription"`
+}
+
+type Rights struct {
+	XMLName          xml.Name          `xml:"http://www.openarchives.org/OAI/2.0/rights/ rights"`
+	RightsReference  *RightsReference  `xml:"http://www.openarchives.org/OAI/2.0/rights/ rightsReference"`
+	RightsDefinition *RightsDefinition `xml:"http://www.openarchives.org/OAI/2.0/rights/ rightsDefinition"`
+}
+
+type RightsReference struct {
+	Ref string `xml:"ref,attr"`
+}
+
+type RightsDefinition struct {
+	Raw []byte `xml:",innerxml"`
+}
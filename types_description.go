@@ -0,0 +1,57 @@
+package oaipmh
+
+import "encoding/xml"
+
+const (
+	oaiIdentifierNamespace = "http://www.openarchives.org/OAI/2.0/oai-identifier"
+	eprintsNamespace       = "http://www.openarchives.org/OAI/1.1/eprints"
+	friendsNamespace       = "http://www.openarchives.org/OAI/2.0/friends/"
+	brandingNamespace      = "http://www.openarchives.org/OAI/2.0/branding/"
+)
+
+type OAIIdentifier struct {
+	XMLName              xml.Name `xml:"http://www.openarchives.org/OAI/2.0/oai-identifier oai-identifier"`
+	Scheme               string   `xml:"scheme"`
+	RepositoryIdentifier string   `xml:"repositoryIdentifier"`
+	Delimiter            string   `xml:"delimiter"`
+	SampleIdentifier     string   `xml:"sampleIdentifier"`
+}
+
+type EPrints struct {
+	XMLName          xml.Name       `xml:"http://www.openarchives.org/OAI/1.1/eprints eprints"`
+	Content          *EPrintsPolicy `xml:"content"`
+	MetadataPolicy   EPrintsPolicy  `xml:"metadataPolicy"`
+	DataPolicy       EPrintsPolicy  `xml:"dataPolicy"`
+	SubmissionPolicy *EPrintsPolicy `xml:"submissionPolicy"`
+	Comments         []string       `xml:"comment"`
+}
+
+type EPrintsPolicy struct {
+	URL  string `xml:"URL"`
+	Text string `xml:"text"`
+}
+
+type Friends struct {
+	XMLName  xml.Name `xml:"http://www.openarchives.org/OAI/2.0/friends/ friends"`
+	BaseURLs []string `xml:"baseURL"`
+}
+
+type Branding struct {
+	XMLName            xml.Name            `xml:"http://www.openarchives.org/OAI/2.0/branding/ branding"`
+	CollectionIcon     *CollectionIcon     `xml:"collectionIcon"`
+	MetadataRenderings []MetadataRendering `xml:"metadataRendering"`
+}
+
+type CollectionIcon struct {
+	URL    string `xml:"url"`
+	Link   string `xml:"link"`
+	Title  string `xml:"title"`
+	Width  int    `xml:"width"`
+	Height int    `xml:"height"`
+}
+
+type MetadataRendering struct {
+	MetadataNamespace string `xml:"metadataNamespace,attr"`
+	MimeType          string `xml:"mimeType,attr"`
+	URL               string `xml:",chardata"`
+}
@@ -7,15 +7,17 @@ import (
 
 type ResponseError struct {
 	XMLName xml.Name `xml:"OAI-PMH"`
-	Error   Error    `xml:"error"`
+	Errors  Errors   `xml:"error"`
 }
 
 type Error struct {
-	XMLName xml.Name `xml:"error"`
-	Message string   `xml:",chardata"`
-	Code    string   `xml:"code,attr"`
+	XMLName xml.Name  `xml:"error"`
+	Message string    `xml:",chardata"`
+	Code    ErrorCode `xml:"code,attr"`
 }
 
+type Errors []Error
+
 type MetadataFormat struct {
 	XMLName           xml.Name `xml:"metadataFormat"`
 	MetadataPrefix    string   `xml:"metadataPrefix"`
@@ -36,47 +38,57 @@ type ListMetadataFormatsOptions struct {
 type ListMetadataFormatsResponse struct {
 	XMLName            xml.Name           `xml:"OAI-PMH"`
 	InterpretedRequest InterpretedRequest `xml:"request"`
-	ResponseDate       string             `xml:"responseDate"`
+	ResponseDate       Datestamp          `xml:"responseDate"`
 	MetadataFormats    []MetadataFormat   `xml:"ListMetadataFormats>metadataFormat"`
 }
 
 type Identify struct {
-	XMLName           xml.Name `xml:"Identify"`
-	RepositoryName    string   `xml:"repositoryName"`
-	BaseURL           string   `xml:"baseURL"`
-	ProtocolVersion   string   `xml:"protocolVersion"`
-	EarliestDatestamp string   `xml:"earliestDatestamp"`
-	DeletedRecord     string   `xml:"deletedRecord"`
-	Granularity       string   `xml:"granularity"`
-	AdminEmail        string   `xml:"adminEmail"`
-	Compression       string   `xml:"compression"`
+	XMLName           xml.Name            `xml:"Identify"`
+	RepositoryName    string              `xml:"repositoryName"`
+	BaseURL           string              `xml:"baseURL"`
+	ProtocolVersion   string              `xml:"protocolVersion"`
+	EarliestDatestamp Datestamp           `xml:"earliestDatestamp"`
+	DeletedRecord     DeletedRecordPolicy `xml:"deletedRecord"`
+	Granularity       Granularity         `xml:"granularity"`
+	AdminEmails       []string            `xml:"adminEmail"`
+	Compressions      []string            `xml:"compression"`
+	Descriptions      []Description       `xml:"description"`
+}
+
+type Description struct {
+	Raw []byte `xml:",innerxml"`
 }
 
 type IdentifyResponse struct {
 	XMLName            xml.Name           `xml:"OAI-PMH"`
 	InterpretedRequest InterpretedRequest `xml:"request"`
-	ResponseDate       string             `xml:"responseDate"`
+	ResponseDate       Datestamp          `xml:"responseDate"`
 	Identify           Identify           `xml:"Identify"`
 }
 
 type RecordHeader struct {
-	XMLName    xml.Name `xml:"header"`
-	Identifier string   `xml:"identifier"`
-	Datestamp  string   `xml:"datestamp"`
-	SetSpec    []string `xml:"setSpec"`
-	Status     string   `xml:"status"`
+	XMLName    xml.Name  `xml:"header"`
+	Identifier string    `xml:"identifier"`
+	Datestamp  Datestamp `xml:"datestamp"`
+	SetSpec    []string  `xml:"setSpec"`
+	Status     string    `xml:"status,attr"`
 }
 
 type Record struct {
 	XMLName  xml.Name     `xml:"record"`
 	Header   RecordHeader `xml:"header"`
 	Metadata Metadata     `xml:"metadata"`
+	About    []About      `xml:"about"`
 }
 
 type Metadata struct {
 	Raw []byte `xml:",innerxml"`
 }
 
+type About struct {
+	Raw []byte `xml:",innerxml"`
+}
+
 type GetRecordOptions struct {
 	Identifier     string
 	MetadataPrefix string
@@ -85,6 +97,7 @@ type GetRecordOptions struct {
 type GetRecordResponse struct {
 	XMLName            xml.Name           `xml:"OAI-PMH"`
 	InterpretedRequest InterpretedRequest `xml:"request"`
+	ResponseDate       Datestamp          `xml:"responseDate"`
 	Record             Record             `xml:"GetRecord>record"`
 }
 
@@ -99,19 +112,23 @@ type ListOptions struct {
 type ListRecordsResponse struct {
 	XMLName            xml.Name           `xml:"OAI-PMH"`
 	InterpretedRequest InterpretedRequest `xml:"request"`
+	ResponseDate       Datestamp          `xml:"responseDate"`
 	Records            []Record           `xml:"ListRecords>record"`
 	ResumptionToken    ResumptionToken    `xml:"ListRecords>resumptionToken"`
 }
 
 type ResumptionToken struct {
-	XMLName        xml.Name `xml:"resumptionToken"`
-	ExpirationDate string   `xml:"expirationDate,attr"`
-	Value          string   `xml:",chardata"`
+	XMLName          xml.Name  `xml:"resumptionToken"`
+	ExpirationDate   Datestamp `xml:"expirationDate,attr"`
+	CompleteListSize *int      `xml:"completeListSize,attr"`
+	Cursor           *int      `xml:"cursor,attr"`
+	Value            string    `xml:",chardata"`
 }
 
 type ListIdentifiersResponse struct {
 	XMLName            xml.Name           `xml:"OAI-PMH"`
 	InterpretedRequest InterpretedRequest `xml:"request"`
+	ResponseDate       Datestamp          `xml:"responseDate"`
 	Headers            []RecordHeader     `xml:"ListIdentifiers>header"`
 	ResumptionToken    ResumptionToken    `xml:"ListIdentifiers>resumptionToken"`
 }
@@ -123,12 +140,14 @@ type ListSetsOptions struct {
 type ListSetsResponse struct {
 	XMLName            xml.Name           `xml:"OAI-PMH"`
 	InterpretedRequest InterpretedRequest `xml:"request"`
+	ResponseDate       Datestamp          `xml:"responseDate"`
 	Sets               []Set              `xml:"ListSets>set"`
 	ResumptionToken    ResumptionToken    `xml:"ListSets>resumptionToken"`
 }
 
 type Set struct {
-	XMLName xml.Name `xml:"set"`
-	SetSpec string   `xml:"setSpec"`
-	SetName string   `xml:"setName"`
+	XMLName      xml.Name      `xml:"set"`
+	SetSpec      string        `xml:"setSpec"`
+	SetName      string        `xml:"setName"`
+	Descriptions []Description `xml:"setDescription"`
 }
@@ -0,0 +1,297 @@
+package oaipmh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// WindowOptions controls a date-windowed harvest. Without a Window size the
+// whole range starts as one window and is only split when it has to be.
+type WindowOptions struct {
+	Window time.Duration
+	// RecordBudget is the most records a window may hold before it is split.
+	// Zero means no budget.
+	RecordBudget int
+	// Concurrency is how many windows are harvested at once. Windows are
+	// still handed over in date order, though records within a window arrive
+	// in the order the repository lists them. With more than one window at a
+	// time, windows after the first are held in memory until their turn.
+	Concurrency int
+}
+
+// window is a half-open range of datestamps, [from, until).
+type window struct {
+	from  time.Time
+	until time.Time
+}
+
+type windowResult struct {
+	records []Record
+	err     error
+}
+
+func (c *Client) HarvestWindows(options ListOptions, windows WindowOptions, handle func(Record) error) error {
+	return c.HarvestWindowsContext(context.Background(), options, windows, handle)
+}
+
+// HarvestWindowsContext harvests ListRecords in From/Until windows spanning
+// options.From, or the repository's earliest datestamp, to options.Until, or
+// now. A window that goes over the record budget, or fails in a way a smaller
+// request could avoid, is halved and each half harvested in its place, down
+// to the repository's granularity. Any other failure ends the harvest.
+func (c *Client) HarvestWindowsContext(ctx context.Context, options ListOptions, windows WindowOptions, handle func(Record) error) error {
+	ranges, unit, err := c.windows(ctx, options, windows.Window)
+
+	if err != nil {
+		return err
+	}
+
+	if windows.Concurrency <= 1 {
+		for _, w := range ranges {
+			if err := c.harvestWindow(ctx, options, w, unit, windows.RecordBudget, map[string]bool{}, handle); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]chan windowResult, len(ranges))
+	slots := make(chan struct{}, windows.Concurrency)
+
+	for i := range results {
+		results[i] = make(chan windowResult, 1)
+	}
+
+	wg.Add(1)
+
+	// Starting a window takes a slot that is only given back once the window
+	// has been handed over, so at most Concurrency windows are ever buffered.
+	go func() {
+		defer wg.Done()
+
+		for i, w := range ranges {
+			if err := acquire(ctx, slots); err != nil {
+				results[i] <- windowResult{err: err}
+				continue
+			}
+
+			wg.Add(1)
+
+			go func(w window, result chan windowResult) {
+				defer wg.Done()
+
+				records := []Record{}
+				err := c.harvestWindow(ctx, options, w, unit, windows.RecordBudget, map[string]bool{}, func(record Record) error {
+					records = append(records, record)
+					return nil
+				})
+				result <- windowResult{records, err}
+			}(w, results[i])
+		}
+	}()
+
+	for i := range ranges {
+		result := <-results[i]
+
+		if result.err != nil {
+			return result.err
+		}
+
+		<-slots
+
+		for _, record := range result.records {
+			if err := handle(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// harvestWindow streams the records of a window to emit, splitting it in half
+// and harvesting each half instead when it fails or goes over budget.
+//
+// With a budget, records are held back until the first page's
+// completeListSize shows the window fits, or, without one, until the window
+// ends within budget. If a window fails after records have been emitted, the
+// identifiers in seen stop its halves from emitting them again.
+func (c *Client) harvestWindow(ctx context.Context, options ListOptions, w window, unit time.Duration, budget int, seen map[string]bool, emit func(Record) error) error {
+	first, second, splittable := w.split(unit)
+	options.From, options.Until, options.ResumptionToken = w.from, w.until.Add(-unit), ""
+
+	it := c.IterateRecordsContext(ctx, options)
+	defer it.Close()
+
+	holding := budget > 0 && splittable
+	overBudget := false
+	held := []Record{}
+
+	for it.Next() {
+		held = append(held, it.Record())
+
+		if holding {
+			size := it.Progress().CompleteListSize
+
+			if len(held) > budget || (size != nil && *size > budget) {
+				overBudget = true
+				break
+			}
+
+			if size == nil {
+				continue
+			}
+
+			holding = false
+		}
+
+		if err := deliver(held, seen, splittable, emit); err != nil {
+			return err
+		}
+
+		held = held[:0]
+	}
+
+	it.Close()
+	err := it.Err()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if !overBudget && err == nil {
+		return deliver(held, seen, splittable, emit)
+	}
+
+	if !overBudget && (!splittable || !splittableError(err)) {
+		return fmt.Errorf("Harvest of window %s to %s failed: %w", w.from.Format(time.RFC3339), w.until.Format(time.RFC3339), err)
+	}
+
+	if err := c.harvestWindow(ctx, options, first, unit, budget, seen, emit); err != nil {
+		return err
+	}
+
+	return c.harvestWindow(ctx, options, second, unit, budget, seen, emit)
+}
+
+// deliver emits records not already seen, remembering them when the window
+// may yet be split.
+func deliver(records []Record, seen map[string]bool, track bool, emit func(Record) error) error {
+	for _, record := range records {
+		if seen[record.Header.Identifier] {
+			continue
+		}
+
+		if track {
+			seen[record.Header.Identifier] = true
+		}
+
+		if err := emit(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splittableError reports whether a failure could be down to the size of the
+// request: broken or expired resumption tokens, timeouts, network errors and
+// server errors. Protocol errors and other HTTP statuses would recur.
+func splittableError(err error) bool {
+	if errors.Is(err, ErrBadResumptionToken) || errors.Is(err, ErrResumptionTokenExpired) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var httpErr *HTTPError
+
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+
+	var urlErr *url.Error
+
+	return errors.As(err, &urlErr)
+}
+
+// windows divides the harvest range into initial windows aligned to the
+// repository's granularity, returning that granularity as a duration.
+func (c *Client) windows(ctx context.Context, options ListOptions, size time.Duration) ([]window, time.Duration, error) {
+	from, until := options.From, options.Until
+
+	if from.IsZero() {
+		response, _, err := c.IdentifyContext(ctx)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if from, err = response.Identify.EarliestDatestamp.Time(); err != nil {
+			return nil, 0, fmt.Errorf("Invalid earliest datestamp: %v", err)
+		}
+	}
+
+	granularity, err := c.repositoryGranularity(ctx)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	unit := time.Second
+
+	if granularity == GranularityDay {
+		unit = 24 * time.Hour
+	}
+
+	if until.IsZero() {
+		until = time.Now()
+	}
+
+	from = from.UTC().Truncate(unit)
+	until = until.UTC().Truncate(unit).Add(unit)
+
+	if size <= 0 {
+		size = until.Sub(from)
+	}
+
+	if size < unit {
+		size = unit
+	}
+
+	size = size.Truncate(unit)
+	windows := []window{}
+
+	for start := from; start.Before(until); start = start.Add(size) {
+		end := start.Add(size)
+
+		if end.After(until) {
+			end = until
+		}
+
+		windows = append(windows, window{start, end})
+	}
+
+	return windows, unit, nil
+}
+
+func (w window) split(unit time.Duration) (window, window, bool) {
+	units := w.until.Sub(w.from) / unit
+
+	if units < 2 {
+		return w, w, false
+	}
+
+	middle := w.from.Add(units / 2 * unit)
+
+	return window{w.from, middle}, window{middle, w.until}, true
+}
@@ -0,0 +1,234 @@
+package oaipmh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	. "gopkg.in/check.v1"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type windowsSuite struct{}
+
+var _ = Suite(&windowsSuite{})
+
+// mockWindowedClient holds one record a day for March 2016 up to the 20th,
+// and fails any ListRecords request spanning more than maxDays days.
+func mockWindowedClient(maxDays int) (*httptest.Server, *Client, *[]string) {
+	mu := sync.Mutex{}
+	requested := []string{}
+
+	server, client, _ := mockQueryClient("verb", func(query url.Values) (int, string) {
+		if query.Get("verb") == "Identify" {
+			return http.StatusOK, `<Identify><earliestDatestamp>2016-03-01</earliestDatestamp><granularity>YYYY-MM-DD</granularity></Identify>`
+		}
+
+		mu.Lock()
+		requested = append(requested, query.Get("from")+"/"+query.Get("until"))
+		mu.Unlock()
+
+		from, _ := time.Parse("2006-01-02", query.Get("from"))
+		until, _ := time.Parse("2006-01-02", query.Get("until"))
+
+		if until.Sub(from) >= time.Duration(maxDays)*24*time.Hour {
+			return http.StatusServiceUnavailable, ""
+		}
+
+		records := []string{}
+
+		for day := from; !day.After(until); day = day.Add(24 * time.Hour) {
+			if day.Day() <= 20 {
+				stamp := day.Format("2006-01-02")
+				records = append(records, fmt.Sprintf(`<record><header><identifier>%s</identifier><datestamp>%s</datestamp></header></record>`, stamp, stamp))
+			}
+		}
+
+		if len(records) == 0 {
+			return http.StatusOK, `<error code="noRecordsMatch">No records</error>`
+		}
+
+		return http.StatusOK, "<ListRecords>" + strings.Join(records, "") + "</ListRecords>"
+	}, WithRetryPolicy(NoRetryPolicy))
+
+	return server, client, &requested
+}
+
+func (s *windowsSuite) harvest(client *Client, windows WindowOptions) ([]string, error) {
+	identifiers := []string{}
+	err := client.HarvestWindows(ListOptions{MetadataPrefix: "oai_dc", Until: time.Date(2016, 3, 31, 0, 0, 0, 0, time.UTC)}, windows, func(record Record) error {
+		identifiers = append(identifiers, record.Header.Identifier)
+		return nil
+	})
+
+	return identifiers, err
+}
+
+func expectedDays() []string {
+	days := []string{}
+
+	for day := 1; day <= 20; day++ {
+		days = append(days, fmt.Sprintf("2016-03-%02d", day))
+	}
+
+	return days
+}
+
+func (s *windowsSuite) TestFailingWindowsAreHalved(c *C) {
+	server, client, requested := mockWindowedClient(8)
+	defer server.Close()
+
+	identifiers, err := s.harvest(client, WindowOptions{})
+
+	c.Assert(err, IsNil)
+	c.Assert(identifiers, DeepEquals, expectedDays())
+	c.Assert((*requested)[0], Equals, "2016-03-01/2016-03-31")
+	c.Assert((*requested)[1], Equals, "2016-03-01/2016-03-15")
+}
+
+func (s *windowsSuite) TestWindowsOverBudgetAreHalved(c *C) {
+	server, client, requested := mockWindowedClient(100)
+	defer server.Close()
+
+	identifiers, err := s.harvest(client, WindowOptions{Window: 10 * 24 * time.Hour, RecordBudget: 3})
+
+	c.Assert(err, IsNil)
+	c.Assert(identifiers, DeepEquals, expectedDays())
+	c.Assert((*requested)[0], Equals, "2016-03-01/2016-03-10")
+	c.Assert((*requested)[1], Equals, "2016-03-01/2016-03-05")
+}
+
+func (s *windowsSuite) TestParallelWindowsAreEmittedInOrder(c *C) {
+	server, client, _ := mockWindowedClient(100)
+	defer server.Close()
+
+	identifiers, err := s.harvest(client, WindowOptions{Window: 2 * 24 * time.Hour, Concurrency: 4})
+
+	c.Assert(err, IsNil)
+	c.Assert(identifiers, DeepEquals, expectedDays())
+}
+
+func (s *windowsSuite) TestUnsplittableFailureIsReturned(c *C) {
+	server, client, _ := mockWindowedClient(0)
+	defer server.Close()
+
+	_, err := s.harvest(client, WindowOptions{Window: 24 * time.Hour, Concurrency: 2})
+
+	c.Assert(err, ErrorMatches, "Harvest of window 2016-03-01T00:00:00Z to 2016-03-02T00:00:00Z failed: .*")
+}
+
+func (s *windowsSuite) TestHandlerErrorStopsHarvest(c *C) {
+	server, client, _ := mockWindowedClient(100)
+	defer server.Close()
+
+	err := client.HarvestWindowsContext(context.Background(), ListOptions{Until: time.Date(2016, 3, 31, 0, 0, 0, 0, time.UTC)}, WindowOptions{Window: 24 * time.Hour, Concurrency: 3}, func(record Record) error {
+		return fmt.Errorf("Stop at %s", record.Header.Identifier)
+	})
+
+	c.Assert(err, ErrorMatches, "Stop at 2016-03-01")
+}
+
+func (s *windowsSuite) TestSplitAlignsToGranularity(c *C) {
+	day := 24 * time.Hour
+	start := time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)
+	first, second, ok := window{start, start.Add(3 * day)}.split(day)
+
+	c.Assert(ok, Equals, true)
+	c.Assert(first.until, Equals, start.Add(day))
+	c.Assert(second.from, Equals, start.Add(day))
+
+	_, _, ok = window{start, start.Add(day)}.split(day)
+
+	c.Assert(ok, Equals, false)
+}
+
+func (s *windowsSuite) TestProtocolErrorsAreNotSplit(c *C) {
+	server, client, requested := mockPagedClient("from", map[string]string{
+		"2006-01-01": `<error code="cannotDisseminateFormat">Unknown format</error>`,
+	}, WithGranularity(GranularityDay))
+	defer server.Close()
+
+	err := client.HarvestWindows(ListOptions{MetadataPrefix: "marc", From: time.Date(2006, 1, 1, 0, 0, 0, 0, time.UTC), Until: time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)}, WindowOptions{}, func(Record) error {
+		return nil
+	})
+
+	c.Assert(err, ErrorMatches, "Harvest of window 2006-01-01T00:00:00Z to 2016-01-02T00:00:00Z failed: .*")
+	c.Assert(errors.Is(err, ErrCannotDisseminateFormat), Equals, true)
+	c.Assert(*requested, HasLen, 1)
+}
+
+func (s *windowsSuite) TestSequentialWindowsAreStreamed(c *C) {
+	server, client, requested := mockPagedClient("resumptionToken", map[string]string{
+		"": `<ListRecords>
+		  <record><header><identifier>a</identifier></header></record>
+		  <resumptionToken>page2</resumptionToken>
+		</ListRecords>`,
+		"page2": `<ListRecords>
+		  <record><header><identifier>b</identifier></header></record>
+		  <resumptionToken/>
+		</ListRecords>`,
+	}, WithGranularity(GranularityDay))
+	defer server.Close()
+
+	requestsAtFirstRecord := 0
+	identifiers := []string{}
+	err := client.HarvestWindows(ListOptions{From: time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC), Until: time.Date(2016, 3, 31, 0, 0, 0, 0, time.UTC)}, WindowOptions{}, func(record Record) error {
+		if len(identifiers) == 0 {
+			requestsAtFirstRecord = len(*requested)
+		}
+
+		identifiers = append(identifiers, record.Header.Identifier)
+		return nil
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(identifiers, DeepEquals, []string{"a", "b"})
+	c.Assert(requestsAtFirstRecord, Equals, 1)
+}
+
+func (s *windowsSuite) TestWindowFailingMidStreamIsNotRepeated(c *C) {
+	server, client, _ := mockQueryClient("from", func(query url.Values) (int, string) {
+		if query.Get("resumptionToken") != "" {
+			return http.StatusServiceUnavailable, ""
+		}
+
+		from, _ := time.Parse("2006-01-02", query.Get("from"))
+		until, _ := time.Parse("2006-01-02", query.Get("until"))
+		records := []string{}
+
+		for day := from; !day.After(until) && len(records) < 2; day = day.Add(24 * time.Hour) {
+			records = append(records, fmt.Sprintf(`<record><header><identifier>%s</identifier></header></record>`, day.Format("2006-01-02")))
+		}
+
+		if until.Sub(from) >= 2*24*time.Hour {
+			records = append(records, `<resumptionToken>more</resumptionToken>`)
+		}
+
+		return http.StatusOK, "<ListRecords>" + strings.Join(records, "") + "</ListRecords>"
+	}, WithGranularity(GranularityDay), WithRetryPolicy(NoRetryPolicy))
+	defer server.Close()
+
+	identifiers := []string{}
+	err := client.HarvestWindows(ListOptions{From: time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC), Until: time.Date(2016, 3, 4, 0, 0, 0, 0, time.UTC)}, WindowOptions{}, func(record Record) error {
+		identifiers = append(identifiers, record.Header.Identifier)
+		return nil
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(identifiers, DeepEquals, []string{"2016-03-01", "2016-03-02", "2016-03-03", "2016-03-04"})
+}
+
+func (s *windowsSuite) TestSplittableErrors(c *C) {
+	c.Assert(splittableError(ErrBadResumptionToken), Equals, true)
+	c.Assert(splittableError(ErrResumptionTokenExpired), Equals, true)
+	c.Assert(splittableError(&HTTPError{StatusCode: http.StatusGatewayTimeout}), Equals, true)
+	c.Assert(splittableError(&url.Error{Op: "Get", URL: "http://example.org/oai", Err: errors.New("connection reset")}), Equals, true)
+	c.Assert(splittableError(context.DeadlineExceeded), Equals, true)
+	c.Assert(splittableError(ErrCannotDisseminateFormat), Equals, false)
+	c.Assert(splittableError(ErrBadArgument), Equals, false)
+	c.Assert(splittableError(&HTTPError{StatusCode: http.StatusUnauthorized}), Equals, false)
+}